@@ -3,103 +3,781 @@ package poetry
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
-	"github.com/paketo-buildpacks/packit"
-	"github.com/paketo-buildpacks/packit/postal"
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/sbom"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
 
 //go:generate faux --interface DependencyManager --output fakes/dependency_manager.go
 //go:generate faux --interface EntryResolver --output fakes/entry_resolver.go
 //go:generate faux --interface InstallProcess --output fakes/install_process.go
-//go:generate faux --interface SitePackageProcess --output fakes/site_package_process.go
+//go:generate faux --interface VerifyProcess --output fakes/verify_process.go
+//go:generate faux --interface PythonVersionLookup --output fakes/python_version_lookup.go
+//go:generate faux --interface VenvInstallProcess --output fakes/venv_install_process.go
+//go:generate faux --interface ExportProcess --output fakes/export_process.go
+//go:generate faux --interface Calculator --output fakes/calculator.go
+//go:generate faux --interface SBOMGenerator --output fakes/sbom_generator.go
 
 // DependencyManager defines the interface for picking the best matching
 // dependency and installing it.
 type DependencyManager interface {
 	Resolve(path, id, version, stack string) (postal.Dependency, error)
 	Deliver(dependency postal.Dependency, cnbPath, destinationPath, platformPath string) error
-	GenerateBillOfMaterials(dependencies ...postal.Dependency) []packit.BOMEntry
 }
 
 // EntryResolver defines the interface for picking the most relevant entry from
 // the Buildpack Plan entries.
 type EntryResolver interface {
+	Resolve(name string, entries []packit.BuildpackPlanEntry, priorities []interface{}) (packit.BuildpackPlanEntry, []packit.BuildpackPlanEntry)
 	MergeLayerTypes(name string, entries []packit.BuildpackPlanEntry) (launch, build bool)
 }
 
 // InstallProcess defines the interface for installing the poetry dependency into a layer.
 type InstallProcess interface {
-	Execute(srcPath, targetLayerPath string) error
+	Execute(srcPath, targetLayerPath, cachePath string) error
 }
 
-// SitePackageProcess defines the interface for looking site packages within a layer.
-type SitePackageProcess interface {
-	Execute(targetLayerPath string) (string, error)
+// VerifyProcess defines the interface for checking that the poetry installed
+// into a layer can be run and is the expected version.
+type VerifyProcess interface {
+	Execute(layerPath, version string) error
 }
 
-func Build(dependencyManager DependencyManager, entryResolver EntryResolver, installProcess InstallProcess, siteProcess SitePackageProcess) packit.BuildFunc {
+// PythonVersionLookup defines the interface for determining the version of
+// the python interpreter that poetry is installed against.
+type PythonVersionLookup interface {
+	Execute() (string, error)
+}
+
+// VenvInstallProcess defines the interface for installing the application's
+// dependencies into a virtual environment within a layer.
+type VenvInstallProcess interface {
+	Execute(workingDir, targetLayerPath string, options VenvInstallOptions) (string, error)
+}
+
+// ExportProcess defines the interface for writing the application's locked
+// dependencies to a requirements file.
+type ExportProcess interface {
+	Execute(workingDir, outputPath string, options ExportOptions) error
+}
+
+// Calculator defines the interface for calculating the checksum of a set of
+// files.
+type Calculator interface {
+	Sum(paths ...string) (string, error)
+}
+
+// SBOMGenerator defines the interface for generating the software bill of
+// materials of the python packages installed in a directory.
+type SBOMGenerator interface {
+	Generate(path string) (sbom.SBOM, error)
+}
+
+const (
+	// cacheMaxAge and cacheMaxSize bound the contents of the poetry-cache
+	// layer, so that it does not grow without limit across builds. See
+	// PruneCache for how files are chosen for removal.
+	cacheMaxAge  = 30 * 24 * time.Hour
+	cacheMaxSize = 1 << 30
+)
+
+func Build(dependencyManager DependencyManager, entryResolver EntryResolver, installProcess InstallProcess, verifyProcess VerifyProcess, pythonVersionLookup PythonVersionLookup, venvInstallProcess VenvInstallProcess, exportProcess ExportProcess, calculator Calculator, sbomGenerator SBOMGenerator, projectParser ProjectParser, logger scribe.Emitter, clock chronos.Clock) packit.BuildFunc {
 	return func(context packit.BuildContext) (packit.BuildResult, error) {
 
-		dependency, err := dependencyManager.Resolve(filepath.Join(context.CNBPath, "buildpack.toml"), "poetry", "*", context.Stack)
+		logger.Title("%s %s", context.BuildpackInfo.Name, context.BuildpackInfo.Version)
+
+		// Entries from version-sources not listed here, such as other buildpacks,
+		// are given the lowest priority. Only the entries that request a version
+		// are resolved, so that any of them takes precedence over the entries
+		// that only require poetry at build or launch.
+		priorities := []interface{}{
+			"BP_POETRY_VERSION",
+			"pyproject.toml",
+		}
+		logger.Process("Resolving Poetry version")
+
+		entry, sortedEntries := entryResolver.Resolve(Poetry, versionedEntries(context.Plan.Entries), priorities)
+		logger.Candidates(sortedEntries)
+
+		version, ok := entry.Metadata["version"].(string)
+		if !ok {
+			version = "default"
+		}
+
+		dependency, err := dependencyManager.Resolve(filepath.Join(context.CNBPath, "buildpack.toml"), "poetry", version, context.Stack)
 		if err != nil {
-			return packit.BuildResult{}, err
+			return packit.BuildResult{}, fmt.Errorf("failed to resolve poetry dependency: %w", err)
 		}
 
-		bom := dependencyManager.GenerateBillOfMaterials(dependency)
+		logger.SelectedDependency(entry, dependency, clock.Now())
 
 		poetryLayer, err := context.Layers.Get("poetry")
 		if err != nil {
-			panic(err)
+			return packit.BuildResult{}, fmt.Errorf("failed to get poetry layer: %w", err)
+		}
+
+		// The cache layer holds the packages downloaded by pip and poetry, so that
+		// later builds need not fetch them again. It is never exposed to later
+		// buildpacks or the application.
+		cacheLayer, err := context.Layers.Get(PoetryCache)
+		if err != nil {
+			return packit.BuildResult{}, fmt.Errorf("failed to get poetry-cache layer: %w", err)
 		}
+		cacheLayer.Cache = true
 
-		poetryLayer.Launch, poetryLayer.Build = entryResolver.MergeLayerTypes("poetry", context.Plan.Entries)
-		poetryLayer.Cache = poetryLayer.Build
+		// Poetry is only available at launch when it is asked for, through
+		// BP_POETRY_LAUNCH or the launch metadata of a buildpack plan entry, so that
+		// it is left out of the run image by default. BP_POETRY_LAUNCH takes
+		// precedence over the buildpack plan.
+		launch, build := entryResolver.MergeLayerTypes(Poetry, context.Plan.Entries)
+		if _, ok := os.LookupEnv("BP_POETRY_LAUNCH"); ok {
+			launch, err = parseBoolEnv("BP_POETRY_LAUNCH")
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
 
-		var buildMetadata = packit.BuildMetadata{}
+		// Since Buildpack API 0.7, the lifecycle ignores the [[bom]] tables of
+		// build.toml and launch.toml, so poetry is recorded in the bill of
+		// materials through the SBOM of its layer alone.
 		var launchMetadata = packit.LaunchMetadata{}
 
-		// Install the poetry source to a temporary dir, since we only need access to
-		// it as an intermediate step when installing poetry.
-		// It doesn't need to go into a layer, since we won't need it in future builds.
-		poetrySrcDir, err := ioutil.TempDir("", "poetry-source")
+		pythonVersion, err := pythonVersionLookup.Execute()
 		if err != nil {
-			return packit.BuildResult{}, fmt.Errorf("failed to create temp poetry-source dir: %w", err)
+			return packit.BuildResult{}, err
 		}
 
-		err = dependencyManager.Deliver(dependency, context.CNBPath, poetrySrcDir, context.Platform.Path)
+		// The environment of the poetry layer depends on whether the application's
+		// dependencies are installed into the poetry-venv layer.
+		venv := containsEntry(context.Plan.Entries, PoetryVenv)
+
+		// Reuse the poetry layer from a previous build when it was installed from
+		// the same dependency, on the same stack, against the same python, and
+		// configured for the same use of the poetry-venv layer. A reused layer is
+		// left untouched, since its files are not restored when it is only needed
+		// at launch. For the same reason, a layer that is needed during the build
+		// is only reused when its files were restored.
+		restored, err := fileExists(filepath.Join(poetryLayer.Path, "bin", "poetry"))
 		if err != nil {
-			panic(err)
+			return packit.BuildResult{}, err
 		}
 
-		err = installProcess.Execute(poetrySrcDir, poetryLayer.Path)
-		if err != nil {
-			panic(err)
+		cachedSHA, ok := poetryLayer.Metadata[DependencySHAKey].(string)
+		cachedStack, _ := poetryLayer.Metadata[StackKey].(string)
+		cachedPythonVersion, _ := poetryLayer.Metadata[PythonVersionKey].(string)
+		cachedVenv, _ := poetryLayer.Metadata[VenvKey].(bool)
+		if ok && (restored || !build) && cachedSHA == dependency.SHA256 && cachedStack == context.Stack && cachedPythonVersion == pythonVersion && cachedVenv == venv {
+			logger.Process("Reusing cached layer %s", poetryLayer.Path)
+			logger.Break()
+		} else {
+			logger.Process("Executing build process")
+
+			poetryLayer, err = poetryLayer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to reset poetry layer: %w", err)
+			}
+
+			// Install the poetry source to a temporary dir, since we only need access to
+			// it as an intermediate step when installing poetry.
+			// It doesn't need to go into a layer, since we won't need it in future builds.
+			poetrySrcDir, err := ioutil.TempDir("", "poetry-source")
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to create temp poetry-source dir: %w", err)
+			}
+			defer os.RemoveAll(poetrySrcDir)
+
+			logger.Subprocess("Installing Poetry %s", dependency.Version)
+
+			duration, err := clock.Measure(func() error {
+				err = dependencyManager.Deliver(dependency, context.CNBPath, poetrySrcDir, context.Platform.Path)
+				if err != nil {
+					return fmt.Errorf("failed to deliver poetry dependency: %w", err)
+				}
+
+				err = installProcess.Execute(poetrySrcDir, poetryLayer.Path, filepath.Join(cacheLayer.Path, "pip"))
+				if err != nil {
+					return fmt.Errorf("failed to install poetry: %w", err)
+				}
+
+				err = verifyProcess.Execute(poetryLayer.Path, dependency.Version)
+				if err != nil {
+					return fmt.Errorf("failed to verify poetry: %w", err)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Action("Completed in %s", duration.Round(time.Millisecond))
+			logger.Break()
+
+			poetryLayer.Metadata = map[string]interface{}{
+				DependencySHAKey: dependency.SHA256,
+				VersionKey:       dependency.Version,
+				StackKey:         context.Stack,
+				PythonVersionKey: pythonVersion,
+				VenvKey:          venv,
+			}
+
+			configurePoetryEnv(poetryLayer, cacheLayer.Path, filepath.Join(context.Layers.Path, PoetryVenv), venv)
+
+			logger.Process("Configuring environment")
+			logger.Subprocess("%s", scribe.NewFormattedMapFromEnvironment(poetryLayer.SharedEnv))
+			logger.Break()
+			logger.Process("Configuring build environment")
+			logger.Subprocess("%s", scribe.NewFormattedMapFromEnvironment(poetryLayer.BuildEnv))
+			logger.Break()
+			logger.Process("Configuring launch environment")
+			logger.Subprocess("%s", scribe.NewFormattedMapFromEnvironment(poetryLayer.LaunchEnv))
+			logger.Break()
 		}
 
-		// Look up the site packages path and prepend it onto $PYTHONPATH
-		sitePackagesPath, err := siteProcess.Execute(poetryLayer.Path)
+		poetryLayer.SBOM, err = layerSBOM(sbomGenerator, filepath.Join(poetryLayer.Path, "venv"), context.BuildpackInfo.SBOMFormats, logger)
 		if err != nil {
-			return packit.BuildResult{}, fmt.Errorf("failed to locate site packages in poetry layer: %w", err)
+			return packit.BuildResult{}, fmt.Errorf("failed to generate SBOM for poetry layer: %w", err)
 		}
-		if sitePackagesPath == "" {
-			return packit.BuildResult{}, fmt.Errorf("poetry installation failed: site packages are missing from the poetry layer")
+
+		poetryLayer.Launch, poetryLayer.Build, poetryLayer.Cache = launch, build, build
+
+		layers := []packit.Layer{poetryLayer}
+
+		requirements := containsEntry(context.Plan.Entries, Requirements)
+
+		var selection dependencySelection
+		if venv || requirements {
+			selection, err = selectDependencies(context.WorkingDir, dependency.Version, projectParser, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
 		}
 
-		poetryLayer.SharedEnv.Prepend("PYTHONPATH", strings.TrimRight(sitePackagesPath, "\n"), ":")
+		if venv {
+			venvLayer, err := context.Layers.Get(PoetryVenv)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to get poetry-venv layer: %w", err)
+			}
+
+			var lockSHA string
+			lockPath := filepath.Join(selection.ProjectPath, PoetryLock)
+			_, err = os.Stat(lockPath)
+			if err != nil && !os.IsNotExist(err) {
+				return packit.BuildResult{}, fmt.Errorf("failed to stat %s: %w", PoetryLock, err)
+			}
+			if err == nil {
+				lockSHA, err = calculator.Sum(lockPath)
+				if err != nil {
+					return packit.BuildResult{}, fmt.Errorf("failed to calculate checksum of %s: %w", PoetryLock, err)
+				}
+			}
+
+			// Reuse the venv layer from a previous build when it was installed from
+			// the same poetry.lock, with the same python and poetry versions and the
+			// same selection of groups and extras.
+			cachedLockSHA, _ := venvLayer.Metadata[LockSHAKey].(string)
+			cachedPythonVersion, _ := venvLayer.Metadata[PythonVersionKey].(string)
+			cachedPoetryVersion, _ := venvLayer.Metadata[PoetryVersionKey].(string)
+			cachedDev, _ := venvLayer.Metadata[DevKey].(bool)
+			cachedWithGroups, _ := venvLayer.Metadata[WithGroupsKey].(string)
+			cachedWithoutGroups, _ := venvLayer.Metadata[WithoutGroupsKey].(string)
+			cachedExtras, _ := venvLayer.Metadata[ExtrasKey].(string)
+
+			// The venv path is read from the metadata, which is restored even when
+			// the files of the layer are not, such as when it is only needed at
+			// launch. A layer that is needed during the build is only reused when its
+			// files were restored.
+			venvLaunch, venvBuild := entryResolver.MergeLayerTypes(PoetryVenv, context.Plan.Entries)
+			venvPath, _ := venvLayer.Metadata[VenvPathKey].(string)
+
+			var restored bool
+			if venvPath != "" {
+				restored, err = fileExists(venvPath)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			if lockSHA != "" && venvPath != "" && (restored || !venvBuild) && cachedLockSHA == lockSHA && cachedPythonVersion == pythonVersion && cachedPoetryVersion == dependency.Version && cachedDev == selection.Dev &&
+				cachedWithGroups == strings.Join(selection.WithGroups, ",") && cachedWithoutGroups == strings.Join(selection.WithoutGroups, ",") && cachedExtras == strings.Join(selection.Extras, ",") {
+				logger.Process("Reusing cached layer %s", venvLayer.Path)
+				logger.Break()
+			} else {
+				logger.Process("Installing application dependencies")
 
-		if poetryLayer.Build {
-			buildMetadata = packit.BuildMetadata{BOM: bom}
+				venvLayer, err = venvLayer.Reset()
+				if err != nil {
+					return packit.BuildResult{}, fmt.Errorf("failed to reset poetry-venv layer: %w", err)
+				}
+
+				// The poetry layer is not on the PATH of this buildpack's own process,
+				// so make poetry available to the install explicitly.
+				env := append(os.Environ(), fmt.Sprintf("PATH=%s", prependPath(filepath.Join(poetryLayer.Path, "bin"), os.Getenv("PATH"))))
+
+				repositories, err := Repositories(context.Platform.Path)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				// Only the names of the repositories are logged, and their
+				// configuration is given to the install alone, so that credentials are
+				// not exposed in the build output or persisted in the layer.
+				if len(repositories) > 0 {
+					var names []string
+					for _, repository := range repositories {
+						names = append(names, repository.Name)
+						env = append(env, repository.Env()...)
+					}
+
+					logger.Subprocess("Using package repositories from service bindings: %s", strings.Join(names, ", "))
+				}
+
+				duration, err := clock.Measure(func() error {
+					venvPath, err = venvInstallProcess.Execute(selection.ProjectPath, venvLayer.Path, VenvInstallOptions{
+						Env:           env,
+						Dev:           selection.Dev,
+						WithGroups:    selection.WithGroups,
+						WithoutGroups: selection.WithoutGroups,
+						Extras:        selection.Extras,
+						CacheDir:      cacheLayer.Path,
+					})
+					return err
+				})
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				logger.Action("Completed in %s", duration.Round(time.Millisecond))
+				logger.Break()
+
+				venvLayer.SharedEnv.Override("VIRTUAL_ENV", venvPath)
+				venvLayer.SharedEnv.Prepend("PATH", filepath.Join(venvPath, "bin"), ":")
+
+				logger.Process("Configuring environment")
+				logger.Subprocess("%s", scribe.NewFormattedMapFromEnvironment(venvLayer.SharedEnv))
+				logger.Break()
+
+				if lockSHA != "" {
+					venvLayer.Metadata = map[string]interface{}{
+						LockSHAKey:       lockSHA,
+						PythonVersionKey: pythonVersion,
+						PoetryVersionKey: dependency.Version,
+						DevKey:           selection.Dev,
+						WithGroupsKey:    strings.Join(selection.WithGroups, ","),
+						WithoutGroupsKey: strings.Join(selection.WithoutGroups, ","),
+						ExtrasKey:        strings.Join(selection.Extras, ","),
+						VenvPathKey:      venvPath,
+					}
+				}
+			}
+
+			venvLayer.SBOM, err = layerSBOM(sbomGenerator, venvPath, context.BuildpackInfo.SBOMFormats, logger)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to generate SBOM for poetry-venv layer: %w", err)
+			}
+
+			venvLayer.Launch, venvLayer.Build = venvLaunch, venvBuild
+			venvLayer.Cache = venvLayer.Build
+
+			// The scripts are only runnable when the venv they are installed into is
+			// available at launch.
+			if venvLayer.Launch {
+				project, err := projectParser.Parse(selection.ProjectPath)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				processes, err := scriptProcesses(project.Scripts, venvPath)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				if len(processes) > 0 {
+					logger.Process("Assigning launch processes")
+					for _, process := range processes {
+						logger.Subprocess("%s: %s", process.Type, process.Command)
+					}
+					logger.Break()
+				}
+
+				launchMetadata.Processes = processes
+			}
+
+			layers = append(layers, venvLayer)
+		}
+
+		if requirements {
+			withoutHashes, err := parseBoolEnv("BP_POETRY_EXPORT_WITHOUT_HASHES")
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			requirementsLayer, err := context.Layers.Get(PoetryRequirements)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to get poetry-requirements layer: %w", err)
+			}
+
+			requirementsLayer, err = requirementsLayer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to reset poetry-requirements layer: %w", err)
+			}
+
+			logger.Process("Exporting application dependencies")
+
+			requirementsPath := filepath.Join(requirementsLayer.Path, "requirements.txt")
+			duration, err := clock.Measure(func() error {
+				return exportProcess.Execute(selection.ProjectPath, requirementsPath, ExportOptions{
+					Env:           append(os.Environ(), fmt.Sprintf("PATH=%s", prependPath(filepath.Join(poetryLayer.Path, "bin"), os.Getenv("PATH")))),
+					Dev:           selection.Dev,
+					WithGroups:    selection.WithGroups,
+					WithoutGroups: selection.WithoutGroups,
+					Extras:        selection.Extras,
+					WithoutHashes: withoutHashes,
+				})
+			})
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Action("Completed in %s", duration.Round(time.Millisecond))
+			logger.Break()
+
+			// Point the pip-install buildpack at the exported requirements, in place
+			// of a requirements.txt in the application directory.
+			requirementsLayer.BuildEnv.Default("BP_PIP_REQUIREMENT", requirementsPath)
+
+			logger.Process("Configuring build environment")
+			logger.Subprocess("%s", scribe.NewFormattedMapFromEnvironment(requirementsLayer.BuildEnv))
+			logger.Break()
+
+			// The requirements are only consumed while later buildpacks install
+			// them, so they are never needed at launch.
+			requirementsLayer.Build = true
+
+			layers = append(layers, requirementsLayer)
+		}
+
+		removed, err := PruneCache(cacheLayer.Path, cacheMaxAge, cacheMaxSize, clock.Now())
+		if err != nil {
+			return packit.BuildResult{}, err
 		}
 
-		if poetryLayer.Launch {
-			launchMetadata = packit.LaunchMetadata{BOM: bom}
+		if removed > 0 {
+			logger.Process("Pruning cache")
+			logger.Subprocess("Removed %d files from %s", removed, cacheLayer.Path)
+			logger.Break()
 		}
+
+		layers = append(layers, cacheLayer)
+
 		return packit.BuildResult{
-			Layers: []packit.Layer{poetryLayer},
+			Layers: layers,
 			Launch: launchMetadata,
-			Build:  buildMetadata,
 		}, nil
 	}
 }
+
+// configurePoetryEnv sets the environment that makes poetry behave
+// predictably for later buildpacks and the application. Defaults are used so
+// that a user can still override the settings.
+func configurePoetryEnv(layer packit.Layer, cachePath, venvPath string, venv bool) {
+	layer.SharedEnv.Prepend("PATH", filepath.Join(layer.Path, "bin"), string(os.PathListSeparator))
+
+	if venv {
+		// Use the virtual environment of the poetry-venv layer, which is
+		// activated through VIRTUAL_ENV, rather than creating another one.
+		layer.SharedEnv.Default("POETRY_VIRTUALENVS_CREATE", "false")
+		layer.SharedEnv.Default("POETRY_VIRTUALENVS_IN_PROJECT", "false")
+		layer.SharedEnv.Default("POETRY_VIRTUALENVS_PATH", venvPath)
+	} else {
+		// Keep any virtual environment that poetry creates within the
+		// application directory, rather than in the user's home directory.
+		layer.SharedEnv.Default("POETRY_VIRTUALENVS_CREATE", "true")
+		layer.SharedEnv.Default("POETRY_VIRTUALENVS_IN_PROJECT", "true")
+	}
+
+	// The poetry-cache layer is only available during the build, so poetry is
+	// given a writable cache directory of its own at launch.
+	layer.BuildEnv.Default("POETRY_CACHE_DIR", cachePath)
+	layer.LaunchEnv.Default("POETRY_CACHE_DIR", "/tmp/pypoetry")
+}
+
+// layerSBOM generates the SBOM of the packages installed in the given path of
+// a layer, in the formats that the buildpack declares. No SBOM is generated
+// when the path does not exist, such as when a layer that is only needed at
+// launch is reused without its files being restored, so that the lifecycle
+// keeps the SBOM of the layer from the previous build.
+func layerSBOM(sbomGenerator SBOMGenerator, path string, formats []string, logger scribe.Emitter) (packit.SBOMFormatter, error) {
+	ok, err := fileExists(path)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	logger.GeneratingSBOM(path)
+
+	content, err := sbomGenerator.Generate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FormattingSBOM(formats...)
+	logger.Break()
+
+	formatter, err := content.InFormats(formats...)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatter, nil
+}
+
+func fileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	return true, nil
+}
+
+// versionedEntries returns the entries that request a version.
+func versionedEntries(entries []packit.BuildpackPlanEntry) []packit.BuildpackPlanEntry {
+	var versioned []packit.BuildpackPlanEntry
+	for _, entry := range entries {
+		if _, ok := entry.Metadata["version"].(string); ok {
+			versioned = append(versioned, entry)
+		}
+	}
+
+	return versioned
+}
+
+func containsEntry(entries []packit.BuildpackPlanEntry, name string) bool {
+	for _, entry := range entries {
+		if entry.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dependencySelection describes which of the application's dependencies are
+// installed or exported, and from which project.
+type dependencySelection struct {
+	ProjectPath   string
+	Dev           bool
+	WithGroups    []string
+	WithoutGroups []string
+	Extras        []string
+}
+
+// selectDependencies reads the project and dependency selection settings from
+// the environment, validating them against the project and the version of
+// poetry that installs them, and checks that the project's poetry.lock is up
+// to date.
+func selectDependencies(workingDir, poetryVersion string, projectParser ProjectParser, logger scribe.Emitter) (dependencySelection, error) {
+	dev, err := parseBoolEnv("BP_POETRY_INSTALL_DEV")
+	if err != nil {
+		return dependencySelection{}, err
+	}
+
+	projectPath, err := ProjectPath(workingDir)
+	if err != nil {
+		return dependencySelection{}, err
+	}
+
+	allowStaleLock, err := parseBoolEnv("BP_POETRY_ALLOW_STALE_LOCK")
+	if err != nil {
+		return dependencySelection{}, err
+	}
+
+	withGroups := parseListEnv("BP_POETRY_INSTALL_GROUPS")
+	withoutGroups := parseListEnv("BP_POETRY_WITHOUT_GROUPS")
+	extras := parseListEnv("BP_POETRY_EXTRAS")
+
+	// Reject names that the project does not declare, since poetry would
+	// otherwise ignore them or fail without saying which names are valid.
+	if len(withGroups) > 0 || len(withoutGroups) > 0 || len(extras) > 0 {
+		project, err := projectParser.Parse(projectPath)
+		if err != nil {
+			return dependencySelection{}, err
+		}
+
+		err = validateNames("BP_POETRY_INSTALL_GROUPS", "group", withGroups, project.Groups)
+		if err != nil {
+			return dependencySelection{}, err
+		}
+
+		err = validateNames("BP_POETRY_WITHOUT_GROUPS", "group", withoutGroups, project.Groups)
+		if err != nil {
+			return dependencySelection{}, err
+		}
+
+		err = validateNames("BP_POETRY_EXTRAS", "extra", extras, project.Extras)
+		if err != nil {
+			return dependencySelection{}, err
+		}
+
+		if len(withGroups) > 0 || len(withoutGroups) > 0 {
+			err = checkGroupSupport(poetryVersion)
+			if err != nil {
+				return dependencySelection{}, err
+			}
+
+			// Poetry 1.2 deprecates the flags that select the development
+			// dependencies in favour of the dev group, so they are selected
+			// alongside the other groups instead.
+			if containsString(project.Groups, "dev") && !containsString(withGroups, "dev") && !containsString(withoutGroups, "dev") {
+				if dev {
+					withGroups = append(withGroups, "dev")
+				} else {
+					withoutGroups = append(withoutGroups, "dev")
+				}
+			}
+		}
+	}
+
+	// Catch a pyproject.toml that was changed without re-locking before poetry
+	// fails on it, or installs dependencies that drift from it.
+	stale, err := LockIsStale(projectPath)
+	if err != nil {
+		return dependencySelection{}, err
+	}
+
+	if stale {
+		if !allowStaleLock {
+			return dependencySelection{}, fmt.Errorf("%s is out of date with %s: run 'poetry lock' to update it, or set BP_POETRY_ALLOW_STALE_LOCK=true to build with it anyway", PoetryLock, PyProject)
+		}
+
+		logger.Process("Warning: %s is out of date with %s, continuing since BP_POETRY_ALLOW_STALE_LOCK is set", PoetryLock, PyProject)
+		logger.Break()
+	}
+
+	return dependencySelection{
+		ProjectPath:   projectPath,
+		Dev:           dev,
+		WithGroups:    withGroups,
+		WithoutGroups: withoutGroups,
+		Extras:        extras,
+	}, nil
+}
+
+// checkGroupSupport returns an error when the given version of poetry cannot
+// select dependency groups, which it can from poetry 1.2.
+func checkGroupSupport(poetryVersion string) error {
+	version, err := semver.NewVersion(poetryVersion)
+	if err != nil {
+		return fmt.Errorf("failed to parse poetry version %q: %w", poetryVersion, err)
+	}
+
+	if version.LessThan(semver.MustParse("1.2.0")) {
+		return fmt.Errorf("invalid BP_POETRY_INSTALL_GROUPS or BP_POETRY_WITHOUT_GROUPS: selecting dependency groups requires poetry 1.2 or later, but poetry %s is installed, set BP_POETRY_VERSION to a later version", poetryVersion)
+	}
+
+	return nil
+}
+
+// scriptProcesses returns a launch process for each of the given poetry
+// scripts. The script named by BP_POETRY_DEFAULT_SCRIPT, or the first script
+// when it is unset, is assigned the web process type and marked as the
+// default process. Any other script named web is rejected, since it would
+// declare the web process a second time.
+func scriptProcesses(scripts []string, venvPath string) ([]packit.Process, error) {
+	defaultScript, ok := os.LookupEnv("BP_POETRY_DEFAULT_SCRIPT")
+	if ok {
+		if !containsString(scripts, defaultScript) {
+			return nil, fmt.Errorf("failed to assign default process: BP_POETRY_DEFAULT_SCRIPT %q is not one of the scripts in [tool.poetry.scripts]: [%s]", defaultScript, strings.Join(scripts, ", "))
+		}
+	} else if len(scripts) > 0 {
+		defaultScript = scripts[0]
+	}
+
+	// The default script takes the web process type, which would then be
+	// declared twice by a script that is itself named web.
+	if defaultScript != "web" && containsString(scripts, "web") {
+		return nil, fmt.Errorf("failed to assign default process: the script \"web\" conflicts with the web process of the default script %q, set BP_POETRY_DEFAULT_SCRIPT=web to make it the default", defaultScript)
+	}
+
+	var processes []packit.Process
+	for _, script := range scripts {
+		process := packit.Process{
+			Type:    script,
+			Command: filepath.Join(venvPath, "bin", script),
+			Direct:  true,
+		}
+
+		if script == defaultScript {
+			process.Type = "web"
+			process.Default = true
+		}
+
+		processes = append(processes, process)
+	}
+
+	return processes, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func prependPath(path, existing string) string {
+	if existing == "" {
+		return path
+	}
+
+	return strings.Join([]string{path, existing}, string(os.PathListSeparator))
+}
+
+func parseBoolEnv(name string) (bool, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return false, nil
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s value %q: %w", name, value, err)
+	}
+
+	return enabled, nil
+}
+
+// parseListEnv returns the names listed in the named environment variable,
+// separated by commas or whitespace.
+func parseListEnv(name string) []string {
+	return strings.FieldsFunc(os.Getenv(name), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+func validateNames(envName, kind string, names, declared []string) error {
+	for _, name := range names {
+		if !containsString(declared, name) {
+			return fmt.Errorf("invalid %s: %s %q is not declared in %s, valid %ss are: [%s]", envName, kind, name, PyProject, kind, strings.Join(declared, ", "))
+		}
+	}
+
+	return nil
+}
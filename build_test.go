@@ -1,12 +1,22 @@
 package poetry_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/paketo-buildpacks/packit"
-	"github.com/paketo-buildpacks/packit/postal"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+	"github.com/paketo-buildpacks/packit/v2/draft"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/sbom"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 	"github.com/paketo-community/poetry"
 	"github.com/paketo-community/poetry/fakes"
 	"github.com/sclevine/spec"
@@ -24,7 +34,15 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		dependencyManager *fakes.DependencyManager
 		entryResolver     *fakes.EntryResolver
 		installProcess    *fakes.InstallProcess
-		siteProcess       *fakes.SitePackageProcess
+		verifyProcess     *fakes.VerifyProcess
+		pythonVersion     *fakes.PythonVersionLookup
+		venvProcess       *fakes.VenvInstallProcess
+		exportProcess     *fakes.ExportProcess
+		calculator        *fakes.Calculator
+		sbomGenerator     *fakes.SBOMGenerator
+		projectParser     *fakes.ProjectParser
+		buffer            *bytes.Buffer
+		timeStamp         time.Time
 
 		build packit.BuildFunc
 	)
@@ -47,32 +65,51 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			Version: "poetry-dependency-version",
 		}
 
-		dependencyManager.GenerateBillOfMaterialsCall.Returns.BOMEntrySlice = []packit.BOMEntry{
-			{
-				Name: "poetry",
-				Metadata: map[string]interface{}{
-					"version": "poetry-dependency-version",
-					"name":    "poetry-dependency-name",
-					"sha256":  "poetry-dependency-sha",
-					"stacks":  []string{"some-stack"},
-					"uri":     "poetry-dependency-uri",
-				},
-			},
-		}
-
 		entryResolver = &fakes.EntryResolver{}
+		entryResolver.ResolveCall.Returns.BuildpackPlanEntry = packit.BuildpackPlanEntry{
+			Name: "poetry",
+		}
 		installProcess = &fakes.InstallProcess{}
-		siteProcess = &fakes.SitePackageProcess{}
-		siteProcess.ExecuteCall.Returns.String = filepath.Join(layersDir, "poetry", "lib", "python1.23", "site-packages")
+		installProcess.ExecuteCall.Stub = func(srcPath, targetLayerPath, cachePath string) error {
+			return os.MkdirAll(filepath.Join(targetLayerPath, "venv"), os.ModePerm)
+		}
+		verifyProcess = &fakes.VerifyProcess{}
+
+		pythonVersion = &fakes.PythonVersionLookup{}
+		pythonVersion.ExecuteCall.Returns.String = "1.23.4"
+
+		venvProcess = &fakes.VenvInstallProcess{}
+		venvProcess.ExecuteCall.Stub = func(workingDir, targetLayerPath string, options poetry.VenvInstallOptions) (string, error) {
+			venvPath := filepath.Join(targetLayerPath, "some-app-py1.23")
+			return venvPath, os.MkdirAll(venvPath, os.ModePerm)
+		}
+
+		exportProcess = &fakes.ExportProcess{}
+
+		calculator = &fakes.Calculator{}
+		calculator.SumCall.Returns.String = "some-lock-sha"
+
+		sbomGenerator = &fakes.SBOMGenerator{}
+
+		projectParser = &fakes.ProjectParser{}
 
-		build = poetry.Build(dependencyManager, entryResolver, installProcess, siteProcess)
+		buffer = bytes.NewBuffer(nil)
+		logger := scribe.NewEmitter(buffer)
+
+		timeStamp = time.Now()
+		clock := chronos.NewClock(func() time.Time {
+			return timeStamp
+		})
+
+		build = poetry.Build(dependencyManager, entryResolver, installProcess, verifyProcess, pythonVersion, venvProcess, exportProcess, calculator, sbomGenerator, projectParser, logger, clock)
 	})
 
 	it("returns a result that installs poetry", func() {
 		result, err := build(packit.BuildContext{
 			BuildpackInfo: packit.BuildpackInfo{
-				Name:    "Some Buildpack",
-				Version: "some-version",
+				Name:        "Some Buildpack",
+				Version:     "some-version",
+				SBOMFormats: []string{sbom.CycloneDXFormat, sbom.SPDXFormat},
 			},
 			CNBPath: cnbDir,
 			Plan: packit.BuildpackPlan{
@@ -86,43 +123,61 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		})
 		Expect(err).NotTo(HaveOccurred())
 
+		expectedSBOM, err := sbom.SBOM{}.InFormats(sbom.CycloneDXFormat, sbom.SPDXFormat)
+		Expect(err).NotTo(HaveOccurred())
+
 		Expect(result).To(Equal(packit.BuildResult{
 			Layers: []packit.Layer{
 				{
 					Name: "poetry",
 					Path: filepath.Join(layersDir, "poetry"),
 					SharedEnv: packit.Environment{
-						"PYTHONPATH.delim":   ":",
-						"PYTHONPATH.prepend": filepath.Join(layersDir, "poetry", "lib/python1.23/site-packages"),
+						"PATH.delim":                            ":",
+						"PATH.prepend":                          filepath.Join(layersDir, "poetry", "bin"),
+						"POETRY_VIRTUALENVS_CREATE.default":     "true",
+						"POETRY_VIRTUALENVS_IN_PROJECT.default": "true",
+					},
+					BuildEnv: packit.Environment{
+						"POETRY_CACHE_DIR.default": filepath.Join(layersDir, "poetry-cache"),
+					},
+					LaunchEnv: packit.Environment{
+						"POETRY_CACHE_DIR.default": "/tmp/pypoetry",
 					},
-					BuildEnv:         packit.Environment{},
-					LaunchEnv:        packit.Environment{},
 					ProcessLaunchEnv: map[string]packit.Environment{},
 					Build:            false,
 					Launch:           false,
 					Cache:            false,
-					// Metadata: map[string]interface{}{
-					// 	miniconda.DepKey: "miniconda3-dependency-sha",
-					// 	"built_at":       timeStamp.Format(time.RFC3339Nano),
-					// },
+					SBOM:             expectedSBOM,
+					Metadata: map[string]interface{}{
+						poetry.DependencySHAKey: "poetry-dependency-sha",
+						poetry.VersionKey:       "poetry-dependency-version",
+						poetry.StackKey:         "some-stack",
+						poetry.PythonVersionKey: "1.23.4",
+						poetry.VenvKey:          false,
+					},
+				},
+				{
+					Name:             "poetry-cache",
+					Path:             filepath.Join(layersDir, "poetry-cache"),
+					SharedEnv:        packit.Environment{},
+					BuildEnv:         packit.Environment{},
+					LaunchEnv:        packit.Environment{},
+					ProcessLaunchEnv: map[string]packit.Environment{},
+					Cache:            true,
 				},
 			},
 		}))
 
 		Expect(dependencyManager.ResolveCall.Receives.Path).To(Equal(filepath.Join(cnbDir, "buildpack.toml")))
 		Expect(dependencyManager.ResolveCall.Receives.Id).To(Equal("poetry"))
-		Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("*"))
+		Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("default"))
 		Expect(dependencyManager.ResolveCall.Receives.Stack).To(Equal("some-stack"))
 
-		Expect(dependencyManager.GenerateBillOfMaterialsCall.Receives.Dependencies).To(Equal([]postal.Dependency{
-			{
-				ID:      "poetry",
-				Name:    "poetry-dependency-name",
-				SHA256:  "poetry-dependency-sha",
-				Stacks:  []string{"some-stack"},
-				URI:     "poetry-dependency-uri",
-				Version: "poetry-dependency-version",
-			},
+		Expect(entryResolver.ResolveCall.Receives.Name).To(Equal("poetry"))
+		Expect(entryResolver.ResolveCall.Receives.Entries).To(BeEmpty())
+		Expect(entryResolver.ResolveCall.Receives.Priorities).To(Equal([]interface{}{
+			"BP_POETRY_VERSION",
+			"pyproject.toml",
 		}))
 
 		Expect(entryResolver.MergeLayerTypesCall.Receives.Name).To(Equal("poetry"))
@@ -145,10 +200,42 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 		Expect(installProcess.ExecuteCall.Receives.SrcPath).To(Equal(dependencyManager.DeliverCall.Receives.DestinationPath))
 		Expect(installProcess.ExecuteCall.Receives.TargetLayerPath).To(Equal(filepath.Join(layersDir, "poetry")))
+		Expect(installProcess.ExecuteCall.Receives.CachePath).To(Equal(filepath.Join(layersDir, "poetry-cache", "pip")))
+
+		Expect(verifyProcess.ExecuteCall.Receives.LayerPath).To(Equal(filepath.Join(layersDir, "poetry")))
+		Expect(verifyProcess.ExecuteCall.Receives.Version).To(Equal("poetry-dependency-version"))
+
+		Expect(sbomGenerator.GenerateCall.CallCount).To(Equal(1))
+		Expect(sbomGenerator.GenerateCall.Receives.Path).To(Equal(filepath.Join(layersDir, "poetry", "venv")))
+
+		Expect(result.Layers[0].SBOM.Formats()).To(Equal([]packit.SBOMFormat{
+			{
+				Extension: sbom.Format(sbom.CycloneDXFormat).Extension(),
+				Content:   sbom.NewFormattedReader(sbom.SBOM{}, sbom.CycloneDXFormat),
+			},
+			{
+				Extension: sbom.Format(sbom.SPDXFormat).Extension(),
+				Content:   sbom.NewFormattedReader(sbom.SBOM{}, sbom.SPDXFormat),
+			},
+		}))
+
+		Expect(pythonVersion.ExecuteCall.CallCount).To(Equal(1))
+		Expect(venvProcess.ExecuteCall.CallCount).To(Equal(0))
 
-		// Expect(buffer.String()).To(ContainSubstring("Some Buildpack some-version"))
-		// Expect(buffer.String()).To(ContainSubstring("Executing build process"))
-		// Expect(buffer.String()).To(ContainSubstring("Installing Miniconda"))
+		Expect(buffer.String()).To(ContainSubstring("Some Buildpack some-version"))
+		Expect(buffer.String()).To(ContainSubstring("Resolving Poetry version"))
+		Expect(buffer.String()).To(ContainSubstring("Candidate version sources (in priority order):"))
+		Expect(buffer.String()).To(ContainSubstring("Selected poetry-dependency-name version (using <unknown>): poetry-dependency-version"))
+		Expect(buffer.String()).To(ContainSubstring("Executing build process"))
+		Expect(buffer.String()).To(ContainSubstring("Installing Poetry poetry-dependency-version"))
+		Expect(buffer.String()).To(ContainSubstring("Completed in 0s"))
+		Expect(buffer.String()).To(ContainSubstring("Configuring environment"))
+		Expect(buffer.String()).To(MatchRegexp(`PATH\s+-> "%s:\$PATH"`, filepath.Join(layersDir, "poetry", "bin")))
+		Expect(buffer.String()).To(MatchRegexp(`POETRY_VIRTUALENVS_IN_PROJECT\s+-> "true"`))
+		Expect(buffer.String()).To(ContainSubstring("Configuring build environment"))
+		Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf(`POETRY_CACHE_DIR -> "%s"`, filepath.Join(layersDir, "poetry-cache"))))
+		Expect(buffer.String()).To(ContainSubstring("Configuring launch environment"))
+		Expect(buffer.String()).To(ContainSubstring(`POETRY_CACHE_DIR -> "/tmp/pypoetry"`))
 	})
 
 	context("when buildplan entries require poetry at build/launch", func() {
@@ -156,7 +243,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			entryResolver.MergeLayerTypesCall.Returns.Launch = true
 			entryResolver.MergeLayerTypesCall.Returns.Build = true
 		})
-		it("returns a layer with build and launch set true and the BOM is set for build and launch", func() {
+		it("returns a layer with build and launch set true", func() {
 			result, err := build(packit.BuildContext{
 				BuildpackInfo: packit.BuildpackInfo{
 					Name:    "Some Buildpack",
@@ -185,56 +272,1762 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
+			expectedSBOM, err := sbom.SBOM{}.InFormats()
+			Expect(err).NotTo(HaveOccurred())
+
 			Expect(result).To(Equal(packit.BuildResult{
 				Layers: []packit.Layer{
 					{
 						Name: "poetry",
 						Path: filepath.Join(layersDir, "poetry"),
 						SharedEnv: packit.Environment{
-							"PYTHONPATH.delim":   ":",
-							"PYTHONPATH.prepend": filepath.Join(layersDir, "poetry", "lib/python1.23/site-packages"),
+							"PATH.delim":                            ":",
+							"PATH.prepend":                          filepath.Join(layersDir, "poetry", "bin"),
+							"POETRY_VIRTUALENVS_CREATE.default":     "true",
+							"POETRY_VIRTUALENVS_IN_PROJECT.default": "true",
+						},
+						BuildEnv: packit.Environment{
+							"POETRY_CACHE_DIR.default": filepath.Join(layersDir, "poetry-cache"),
+						},
+						LaunchEnv: packit.Environment{
+							"POETRY_CACHE_DIR.default": "/tmp/pypoetry",
 						},
-						BuildEnv:         packit.Environment{},
-						LaunchEnv:        packit.Environment{},
 						ProcessLaunchEnv: map[string]packit.Environment{},
 						Build:            true,
 						Launch:           true,
 						Cache:            true,
-						// Metadata: map[string]interface{}{
-						// 	poetry.DepKey: "poetry-dependency-sha",
-						// 	"built_at":       timeStamp.Format(time.RFC3339Nano),
-						// },
+						SBOM:             expectedSBOM,
+						Metadata: map[string]interface{}{
+							poetry.DependencySHAKey: "poetry-dependency-sha",
+							poetry.VersionKey:       "poetry-dependency-version",
+							poetry.StackKey:         "some-stack",
+							poetry.PythonVersionKey: "1.23.4",
+							poetry.VenvKey:          false,
+						},
+					},
+					{
+						Name:             "poetry-cache",
+						Path:             filepath.Join(layersDir, "poetry-cache"),
+						SharedEnv:        packit.Environment{},
+						BuildEnv:         packit.Environment{},
+						LaunchEnv:        packit.Environment{},
+						ProcessLaunchEnv: map[string]packit.Environment{},
+						Cache:            true,
 					},
 				},
-				Launch: packit.LaunchMetadata{
-					BOM: []packit.BOMEntry{
-						{
-							Name: "poetry",
-							Metadata: map[string]interface{}{
-								"version": "poetry-dependency-version",
-								"name":    "poetry-dependency-name",
-								"sha256":  "poetry-dependency-sha",
-								"stacks":  []string{"some-stack"},
-								"uri":     "poetry-dependency-uri",
-							},
+			}))
+		})
+	})
+
+	context("when BP_POETRY_LAUNCH is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_POETRY_LAUNCH", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_POETRY_LAUNCH")).To(Succeed())
+		})
+
+		it("makes poetry available at launch", func() {
+			result, err := build(packit.BuildContext{
+				CNBPath: cnbDir,
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "poetry"},
+					},
+				},
+				Layers: packit.Layers{Path: layersDir},
+				Stack:  "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].Launch).To(BeTrue())
+		})
+
+		context("when it is false and a buildpack plan entry requires poetry at launch", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_LAUNCH", "false")).To(Succeed())
+
+				entryResolver.MergeLayerTypesCall.Returns.Launch = true
+				entryResolver.MergeLayerTypesCall.Returns.Build = true
+			})
+
+			it("leaves poetry out of the launch image", func() {
+				result, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].Launch).To(BeFalse())
+				Expect(result.Layers[0].Build).To(BeTrue())
+			})
+		})
+
+		context("when it is not a boolean", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_LAUNCH", "not-a-bool")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
 						},
 					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError(ContainSubstring(`failed to parse BP_POETRY_LAUNCH value "not-a-bool"`)))
+			})
+		})
+	})
+
+	context("when rebuilding a layer", func() {
+		it.Before(func() {
+			entryResolver.MergeLayerTypesCall.Returns.Build = true
+
+			Expect(os.WriteFile(filepath.Join(layersDir, "poetry.toml"), []byte(`[metadata]
+dependency-sha = "poetry-dependency-sha"
+version = "poetry-dependency-version"
+stack = "some-stack"
+python-version = "1.23.4"
+`), 0600)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(layersDir, "poetry", "bin"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(layersDir, "poetry", "bin", "poetry"), []byte("some-poetry"), 0755)).To(Succeed())
+		})
+
+		it("reuses the cached layer without reinstalling poetry", func() {
+			result, err := build(packit.BuildContext{
+				CNBPath: cnbDir,
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "poetry"},
+					},
 				},
-				Build: packit.BuildMetadata{
-					BOM: []packit.BOMEntry{
-						{
-							Name: "poetry",
-							Metadata: map[string]interface{}{
-								"version": "poetry-dependency-version",
-								"name":    "poetry-dependency-name",
-								"sha256":  "poetry-dependency-sha",
-								"stacks":  []string{"some-stack"},
-								"uri":     "poetry-dependency-uri",
+				Layers: packit.Layers{Path: layersDir},
+				Stack:  "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(2))
+			layer := result.Layers[0]
+			Expect(layer.Name).To(Equal("poetry"))
+			Expect(layer.Build).To(BeTrue())
+			Expect(layer.Cache).To(BeTrue())
+			Expect(layer.Metadata).To(Equal(map[string]interface{}{
+				poetry.DependencySHAKey: "poetry-dependency-sha",
+				poetry.VersionKey:       "poetry-dependency-version",
+				poetry.StackKey:         "some-stack",
+				poetry.PythonVersionKey: "1.23.4",
+			}))
+
+			Expect(dependencyManager.DeliverCall.CallCount).To(Equal(0))
+			Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
+			Expect(verifyProcess.ExecuteCall.CallCount).To(Equal(0))
+			Expect(filepath.Join(layersDir, "poetry", "bin", "poetry")).To(BeAnExistingFile())
+			Expect(layer.SharedEnv).To(BeEmpty())
+
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Reusing cached layer %s", filepath.Join(layersDir, "poetry"))))
+			Expect(buffer.String()).NotTo(ContainSubstring("Executing build process"))
+		})
+
+		context("when the layer was configured for a poetry-venv layer", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(layersDir, "poetry.toml"), []byte(`[metadata]
+dependency-sha = "poetry-dependency-sha"
+version = "poetry-dependency-version"
+stack = "some-stack"
+python-version = "1.23.4"
+venv = true
+`), 0600)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "poetry", "env"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "poetry", "env", "POETRY_VIRTUALENVS_CREATE.default"), []byte("false"), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "poetry", "env", "POETRY_VIRTUALENVS_PATH.default"), []byte("some-venv-path"), 0600)).To(Succeed())
+			})
+
+			it("reinstalls poetry with the new environment", func() {
+				result, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+				Expect(result.Layers[0].Metadata[poetry.VenvKey]).To(BeFalse())
+				Expect(result.Layers[0].SharedEnv).To(Equal(packit.Environment{
+					"PATH.delim":                            ":",
+					"PATH.prepend":                          filepath.Join(layersDir, "poetry", "bin"),
+					"POETRY_VIRTUALENVS_CREATE.default":     "true",
+					"POETRY_VIRTUALENVS_IN_PROJECT.default": "true",
+				}))
+				Expect(filepath.Join(layersDir, "poetry", "env", "POETRY_VIRTUALENVS_PATH.default")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("when the python version has changed", func() {
+			it.Before(func() {
+				pythonVersion.ExecuteCall.Returns.String = "4.5.6"
+			})
+
+			it("reinstalls poetry into the layer", func() {
+				result, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(2))
+				Expect(result.Layers[0].Metadata[poetry.PythonVersionKey]).To(Equal("4.5.6"))
+				Expect(filepath.Join(layersDir, "poetry", "bin", "poetry")).NotTo(BeAnExistingFile())
+
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+			})
+		})
+
+		context("when the dependency has changed", func() {
+			it.Before(func() {
+				dependencyManager.ResolveCall.Returns.Dependency.SHA256 = "some-other-sha"
+			})
+
+			it("reinstalls poetry into the layer", func() {
+				result, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(2))
+				Expect(result.Layers[0].Metadata[poetry.DependencySHAKey]).To(Equal("some-other-sha"))
+
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+			})
+		})
+		context("when the layer was only needed at launch and its files were not restored", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(layersDir, "poetry", "bin"))).To(Succeed())
+			})
+
+			it("reinstalls poetry into the layer for the build", func() {
+				result, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].Build).To(BeTrue())
+				Expect(dependencyManager.DeliverCall.CallCount).To(Equal(1))
+				Expect(installProcess.ExecuteCall.CallCount).To(Equal(1))
+				Expect(buffer.String()).NotTo(ContainSubstring("Reusing cached layer"))
+			})
+
+			context("when the layer is still only needed at launch", func() {
+				it.Before(func() {
+					entryResolver.MergeLayerTypesCall.Returns.Launch = true
+					entryResolver.MergeLayerTypesCall.Returns.Build = false
+				})
+
+				it("reuses the layer from the previous image", func() {
+					result, err := build(packit.BuildContext{
+						CNBPath: cnbDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry"},
 							},
 						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Layers[0].Launch).To(BeTrue())
+					Expect(result.Layers[0].Build).To(BeFalse())
+					Expect(installProcess.ExecuteCall.CallCount).To(Equal(0))
+					Expect(buffer.String()).To(ContainSubstring("Reusing cached layer"))
+				})
+			})
+		})
+	})
+
+	context("when the cache layer contains stale files", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(layersDir, "poetry-cache", "pip"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(layersDir, "poetry-cache", "pip", "stale-wheel"), nil, 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(layersDir, "poetry-cache", "pip", "fresh-wheel"), nil, 0600)).To(Succeed())
+
+			staleTime := timeStamp.Add(-60 * 24 * time.Hour)
+			Expect(os.Chtimes(filepath.Join(layersDir, "poetry-cache", "pip", "stale-wheel"), staleTime, staleTime)).To(Succeed())
+		})
+
+		it("prunes them from the cache layer", func() {
+			_, err := build(packit.BuildContext{
+				CNBPath: cnbDir,
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "poetry"},
+					},
+				},
+				Layers: packit.Layers{Path: layersDir},
+				Stack:  "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layersDir, "poetry-cache", "pip", "stale-wheel")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(layersDir, "poetry-cache", "pip", "fresh-wheel")).To(BeAnExistingFile())
+
+			Expect(buffer.String()).To(ContainSubstring("Pruning cache"))
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Removed 1 files from %s", filepath.Join(layersDir, "poetry-cache"))))
+		})
+	})
+
+	context("when the buildpack plan requires poetry-venv", func() {
+		var workingDir string
+
+		it.Before(func() {
+			var err error
+			workingDir, err = os.MkdirTemp("", "working-dir")
+			Expect(err).NotTo(HaveOccurred())
+
+			entryResolver.MergeLayerTypesCall.Stub = func(name string, entries []packit.BuildpackPlanEntry) (bool, bool) {
+				switch name {
+				case poetry.PoetryVenv:
+					return true, true
+				case poetry.Poetry:
+					return false, true
+				}
+				return false, false
+			}
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(workingDir)).To(Succeed())
+		})
+
+		it("installs the application's dependencies into a venv layer", func() {
+			result, err := build(packit.BuildContext{
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+						{Name: "poetry-venv"},
 					},
 				},
+				Layers: packit.Layers{Path: layersDir},
+				Stack:  "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedSBOM, err := sbom.SBOM{}.InFormats()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+			Expect(result.Layers[1]).To(Equal(packit.Layer{
+				Name: "poetry-venv",
+				Path: filepath.Join(layersDir, "poetry-venv"),
+				SharedEnv: packit.Environment{
+					"VIRTUAL_ENV.override": filepath.Join(layersDir, "poetry-venv", "some-app-py1.23"),
+					"PATH.delim":           ":",
+					"PATH.prepend":         filepath.Join(layersDir, "poetry-venv", "some-app-py1.23", "bin"),
+				},
+				BuildEnv:         packit.Environment{},
+				LaunchEnv:        packit.Environment{},
+				ProcessLaunchEnv: map[string]packit.Environment{},
+				Build:            true,
+				Launch:           true,
+				Cache:            true,
+				SBOM:             expectedSBOM,
 			}))
+
+			Expect(venvProcess.ExecuteCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(venvProcess.ExecuteCall.Receives.TargetLayerPath).To(Equal(filepath.Join(layersDir, "poetry-venv")))
+			Expect(venvProcess.ExecuteCall.Receives.Options.Dev).To(BeFalse())
+			Expect(venvProcess.ExecuteCall.Receives.Options.CacheDir).To(Equal(filepath.Join(layersDir, "poetry-cache")))
+			Expect(buffer.String()).To(ContainSubstring("Installing application dependencies"))
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf(`VIRTUAL_ENV -> "%s"`, filepath.Join(layersDir, "poetry-venv", "some-app-py1.23"))))
+			Expect(venvProcess.ExecuteCall.Receives.Options.Env).To(ContainElement(MatchRegexp(`^PATH=%s:`, filepath.Join(layersDir, "poetry", "bin"))))
+
+			Expect(result.Layers[0].SharedEnv).To(Equal(packit.Environment{
+				"PATH.delim":                            ":",
+				"PATH.prepend":                          filepath.Join(layersDir, "poetry", "bin"),
+				"POETRY_VIRTUALENVS_CREATE.default":     "false",
+				"POETRY_VIRTUALENVS_IN_PROJECT.default": "false",
+				"POETRY_VIRTUALENVS_PATH.default":       filepath.Join(layersDir, "poetry-venv"),
+			}))
+			Expect(result.Layers[0].Build).To(BeTrue())
+			Expect(result.Layers[0].Cache).To(BeTrue())
+			Expect(result.Layers[2].Name).To(Equal("poetry-cache"))
+
+			Expect(sbomGenerator.GenerateCall.CallCount).To(Equal(2))
+			Expect(sbomGenerator.GenerateCall.Receives.Path).To(Equal(filepath.Join(layersDir, "poetry-venv", "some-app-py1.23")))
+		})
+
+		context("when the application has a poetry.lock", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "poetry.lock"), []byte("[metadata]\nlock-version = \"1.1\"\n"), 0600)).To(Succeed())
+			})
+
+			it("records the lock checksum in the venv layer metadata", func() {
+				result, err := build(packit.BuildContext{
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+							{Name: "poetry-venv"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(3))
+				Expect(result.Layers[1].Metadata).To(Equal(map[string]interface{}{
+					poetry.LockSHAKey:       "some-lock-sha",
+					poetry.PythonVersionKey: "1.23.4",
+					poetry.PoetryVersionKey: "poetry-dependency-version",
+					poetry.DevKey:           false,
+					poetry.WithGroupsKey:    "",
+					poetry.WithoutGroupsKey: "",
+					poetry.ExtrasKey:        "",
+					poetry.VenvPathKey:      filepath.Join(layersDir, "poetry-venv", "some-app-py1.23"),
+				}))
+
+				Expect(calculator.SumCall.Receives.Paths).To(Equal([]string{filepath.Join(workingDir, "poetry.lock")}))
+			})
+
+			context("when the venv layer was installed from the same lock", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(layersDir, "poetry-venv.toml"), []byte(fmt.Sprintf(`[metadata]
+lock-sha = "some-lock-sha"
+python-version = "1.23.4"
+poetry-version = "poetry-dependency-version"
+dev = false
+venv-path = %q
+`, filepath.Join(layersDir, "poetry-venv", "some-venv"))), 0600)).To(Succeed())
+					Expect(os.MkdirAll(filepath.Join(layersDir, "poetry-venv", "some-venv"), os.ModePerm)).To(Succeed())
+
+					projectParser.ParseCall.Returns.ProjectMetadata = poetry.ProjectMetadata{
+						Detected: true,
+						Scripts:  []string{"some-script"},
+					}
+				})
+
+				it("reuses the venv layer", func() {
+					result, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Layers).To(HaveLen(3))
+					Expect(result.Layers[1].SharedEnv).To(BeEmpty())
+					Expect(result.Layers[1].Launch).To(BeTrue())
+					Expect(result.Layers[1].Build).To(BeTrue())
+					Expect(result.Layers[1].Cache).To(BeTrue())
+
+					Expect(result.Launch.Processes).To(Equal([]packit.Process{
+						{
+							Type:    "web",
+							Command: filepath.Join(layersDir, "poetry-venv", "some-venv", "bin", "some-script"),
+							Direct:  true,
+							Default: true,
+						},
+					}))
+					Expect(sbomGenerator.GenerateCall.Receives.Path).To(Equal(filepath.Join(layersDir, "poetry-venv", "some-venv")))
+
+					Expect(venvProcess.ExecuteCall.CallCount).To(Equal(0))
+					Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Reusing cached layer %s", filepath.Join(layersDir, "poetry-venv"))))
+				})
+
+				context("when the layer was only needed at launch and its files were not restored", func() {
+					it.Before(func() {
+						Expect(os.RemoveAll(filepath.Join(layersDir, "poetry-venv"))).To(Succeed())
+					})
+
+					it("reinstalls the dependencies for the build", func() {
+						result, err := build(packit.BuildContext{
+							CNBPath:    cnbDir,
+							WorkingDir: workingDir,
+							Plan: packit.BuildpackPlan{
+								Entries: []packit.BuildpackPlanEntry{
+									{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+									{Name: "poetry-venv"},
+								},
+							},
+							Layers: packit.Layers{Path: layersDir},
+							Stack:  "some-stack",
+						})
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(result.Layers[1].Build).To(BeTrue())
+						Expect(result.Layers[1].Metadata[poetry.VenvPathKey]).To(Equal(filepath.Join(layersDir, "poetry-venv", "some-app-py1.23")))
+						Expect(venvProcess.ExecuteCall.CallCount).To(Equal(1))
+					})
+
+					context("when the layer is still only needed at launch", func() {
+						it.Before(func() {
+							entryResolver.MergeLayerTypesCall.Stub = func(name string, entries []packit.BuildpackPlanEntry) (bool, bool) {
+								if name == poetry.PoetryVenv {
+									return true, false
+								}
+								return false, true
+							}
+						})
+
+						it("reuses the layer from the previous image", func() {
+							result, err := build(packit.BuildContext{
+								CNBPath:    cnbDir,
+								WorkingDir: workingDir,
+								Plan: packit.BuildpackPlan{
+									Entries: []packit.BuildpackPlanEntry{
+										{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+										{Name: "poetry-venv"},
+									},
+								},
+								Layers: packit.Layers{Path: layersDir},
+								Stack:  "some-stack",
+							})
+							Expect(err).NotTo(HaveOccurred())
+
+							Expect(result.Layers[1].Launch).To(BeTrue())
+							Expect(result.Layers[1].Build).To(BeFalse())
+							Expect(venvProcess.ExecuteCall.CallCount).To(Equal(0))
+						})
+					})
+				})
+			})
+
+			context("when the venv layer was installed from a different lock", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(layersDir, "poetry-venv.toml"), []byte(`[metadata]
+lock-sha = "some-other-lock-sha"
+python-version = "1.23.4"
+poetry-version = "poetry-dependency-version"
+dev = false
+`), 0600)).To(Succeed())
+				})
+
+				it("reinstalls the dependencies", func() {
+					result, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Layers[1].Metadata[poetry.LockSHAKey]).To(Equal("some-lock-sha"))
+					Expect(venvProcess.ExecuteCall.CallCount).To(Equal(1))
+				})
+			})
+		})
+
+		context("when the project declares scripts", func() {
+			it.Before(func() {
+				projectParser.ParseCall.Returns.ProjectMetadata = poetry.ProjectMetadata{
+					Detected: true,
+					Scripts:  []string{"some-script", "other-script"},
+				}
+			})
+
+			it("assigns a launch process for each script", func() {
+				result, err := build(packit.BuildContext{
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+							{Name: "poetry-venv"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(projectParser.ParseCall.Receives.Path).To(Equal(workingDir))
+				Expect(result.Launch.Processes).To(Equal([]packit.Process{
+					{
+						Type:    "web",
+						Command: filepath.Join(layersDir, "poetry-venv", "some-app-py1.23", "bin", "some-script"),
+						Direct:  true,
+						Default: true,
+					},
+					{
+						Type:    "other-script",
+						Command: filepath.Join(layersDir, "poetry-venv", "some-app-py1.23", "bin", "other-script"),
+						Direct:  true,
+					},
+				}))
+				Expect(buffer.String()).To(ContainSubstring("Assigning launch processes"))
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("web: %s", filepath.Join(layersDir, "poetry-venv", "some-app-py1.23", "bin", "some-script"))))
+			})
+
+			context("when BP_POETRY_DEFAULT_SCRIPT is set", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_POETRY_DEFAULT_SCRIPT", "other-script")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_POETRY_DEFAULT_SCRIPT")).To(Succeed())
+				})
+
+				it("assigns the named script as the web process", func() {
+					result, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Launch.Processes).To(Equal([]packit.Process{
+						{
+							Type:    "some-script",
+							Command: filepath.Join(layersDir, "poetry-venv", "some-app-py1.23", "bin", "some-script"),
+							Direct:  true,
+						},
+						{
+							Type:    "web",
+							Command: filepath.Join(layersDir, "poetry-venv", "some-app-py1.23", "bin", "other-script"),
+							Direct:  true,
+							Default: true,
+						},
+					}))
+				})
+			})
+
+			context("when the default script is named web", func() {
+				it.Before(func() {
+					projectParser.ParseCall.Returns.ProjectMetadata.Scripts = []string{"other-script", "web"}
+					Expect(os.Setenv("BP_POETRY_DEFAULT_SCRIPT", "web")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_POETRY_DEFAULT_SCRIPT")).To(Succeed())
+				})
+
+				it("assigns a single web process", func() {
+					result, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Launch.Processes).To(Equal([]packit.Process{
+						{
+							Type:    "other-script",
+							Command: filepath.Join(layersDir, "poetry-venv", "some-app-py1.23", "bin", "other-script"),
+							Direct:  true,
+						},
+						{
+							Type:    "web",
+							Command: filepath.Join(layersDir, "poetry-venv", "some-app-py1.23", "bin", "web"),
+							Direct:  true,
+							Default: true,
+						},
+					}))
+				})
+			})
+
+			context("when the venv is not required at launch", func() {
+				it.Before(func() {
+					entryResolver.MergeLayerTypesCall.Stub = func(name string, entries []packit.BuildpackPlanEntry) (bool, bool) {
+						return false, name == poetry.PoetryVenv
+					}
+				})
+
+				it("does not assign launch processes", func() {
+					result, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Launch.Processes).To(BeEmpty())
+					Expect(projectParser.ParseCall.CallCount).To(Equal(0))
+				})
+			})
+		})
+
+		context("when BP_POETRY_PROJECT_PATH is set", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "some-project"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project", "pyproject.toml"), nil, 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-project", "poetry.lock"), nil, 0600)).To(Succeed())
+
+				Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "some-project")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_POETRY_PROJECT_PATH")).To(Succeed())
+			})
+
+			it("installs the dependencies of the project in that directory", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+							{Name: "poetry-venv"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(calculator.SumCall.Receives.Paths).To(Equal([]string{filepath.Join(workingDir, "some-project", "poetry.lock")}))
+				Expect(venvProcess.ExecuteCall.Receives.WorkingDir).To(Equal(filepath.Join(workingDir, "some-project")))
+				Expect(projectParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "some-project")))
+			})
+		})
+
+		context("when the poetry.lock is out of date with the pyproject.toml", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte("[tool.poetry.dependencies]\nrequests = \"^2.26\"\n"), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "poetry.lock"), []byte("[metadata]\ncontent-hash = \"some-outdated-hash\"\n"), 0600)).To(Succeed())
+			})
+
+			it("fails the build before installing the dependencies", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+							{Name: "poetry-venv"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError("poetry.lock is out of date with pyproject.toml: run 'poetry lock' to update it, or set BP_POETRY_ALLOW_STALE_LOCK=true to build with it anyway"))
+
+				Expect(venvProcess.ExecuteCall.CallCount).To(Equal(0))
+			})
+
+			context("when BP_POETRY_ALLOW_STALE_LOCK is set", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_POETRY_ALLOW_STALE_LOCK", "true")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_POETRY_ALLOW_STALE_LOCK")).To(Succeed())
+				})
+
+				it("warns and installs the dependencies anyway", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(venvProcess.ExecuteCall.CallCount).To(Equal(1))
+					Expect(buffer.String()).To(ContainSubstring("Warning: poetry.lock is out of date with pyproject.toml, continuing since BP_POETRY_ALLOW_STALE_LOCK is set"))
+				})
+			})
+		})
+
+		context("when there is a service binding for a package repository", func() {
+			var platformDir string
+
+			it.Before(func() {
+				var err error
+				platformDir, err = os.MkdirTemp("", "platform")
+				Expect(err).NotTo(HaveOccurred())
+
+				bindingDir := filepath.Join(platformDir, "bindings", "private-index")
+				Expect(os.MkdirAll(bindingDir, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(bindingDir, "type"), []byte("poetry"), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(bindingDir, "url"), []byte("https://index.example.com/simple"), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(bindingDir, "username"), []byte("some-user"), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(bindingDir, "password"), []byte("some-secret"), 0600)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.RemoveAll(platformDir)).To(Succeed())
+			})
+
+			it("configures the repository for the install without exposing its credentials", func() {
+				result, err := build(packit.BuildContext{
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+							{Name: "poetry-venv"},
+						},
+					},
+					Platform: packit.Platform{Path: platformDir},
+					Layers:   packit.Layers{Path: layersDir},
+					Stack:    "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(venvProcess.ExecuteCall.Receives.Options.Env).To(ContainElements(
+					"POETRY_REPOSITORIES_PRIVATE_INDEX_URL=https://index.example.com/simple",
+					"POETRY_HTTP_BASIC_PRIVATE_INDEX_USERNAME=some-user",
+					"POETRY_HTTP_BASIC_PRIVATE_INDEX_PASSWORD=some-secret",
+				))
+
+				Expect(buffer.String()).To(ContainSubstring("Using package repositories from service bindings: private-index"))
+				Expect(buffer.String()).NotTo(ContainSubstring("some-secret"))
+				Expect(fmt.Sprintf("%v", result.Layers)).NotTo(ContainSubstring("some-secret"))
+			})
+		})
+
+		context("when BP_POETRY_INSTALL_DEV is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_INSTALL_DEV", "true")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_POETRY_INSTALL_DEV")).To(Succeed())
+			})
+
+			it("installs the development dependencies too", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+							{Name: "poetry-venv"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(venvProcess.ExecuteCall.Receives.Options.Dev).To(BeTrue())
+			})
+		})
+
+		context("when dependency groups and extras are selected", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_INSTALL_GROUPS", "docs, lint")).To(Succeed())
+				Expect(os.Setenv("BP_POETRY_WITHOUT_GROUPS", "dev")).To(Succeed())
+				Expect(os.Setenv("BP_POETRY_EXTRAS", "postgres")).To(Succeed())
+
+				projectParser.ParseCall.Returns.ProjectMetadata = poetry.ProjectMetadata{
+					Detected: true,
+					Groups:   []string{"main", "dev", "docs", "lint"},
+					Extras:   []string{"postgres", "mysql"},
+				}
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "poetry.lock"), []byte("[metadata]\nlock-version = \"1.1\"\n"), 0600)).To(Succeed())
+
+				dependencyManager.ResolveCall.Returns.Dependency.Version = "1.2.0"
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_POETRY_INSTALL_GROUPS")).To(Succeed())
+				Expect(os.Unsetenv("BP_POETRY_WITHOUT_GROUPS")).To(Succeed())
+				Expect(os.Unsetenv("BP_POETRY_EXTRAS")).To(Succeed())
+			})
+
+			it("installs the selected groups and extras", func() {
+				result, err := build(packit.BuildContext{
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+							{Name: "poetry-venv"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(projectParser.ParseCall.Receives.Path).To(Equal(workingDir))
+				Expect(venvProcess.ExecuteCall.Receives.Options.WithGroups).To(Equal([]string{"docs", "lint"}))
+				Expect(venvProcess.ExecuteCall.Receives.Options.WithoutGroups).To(Equal([]string{"dev"}))
+				Expect(venvProcess.ExecuteCall.Receives.Options.Extras).To(Equal([]string{"postgres"}))
+
+				Expect(result.Layers[1].Metadata[poetry.WithGroupsKey]).To(Equal("docs,lint"))
+				Expect(result.Layers[1].Metadata[poetry.WithoutGroupsKey]).To(Equal("dev"))
+				Expect(result.Layers[1].Metadata[poetry.ExtrasKey]).To(Equal("postgres"))
+			})
+
+			context("when the dev group is not selected", func() {
+				it.Before(func() {
+					Expect(os.Unsetenv("BP_POETRY_WITHOUT_GROUPS")).To(Succeed())
+				})
+
+				it("leaves the dev group out, as BP_POETRY_INSTALL_DEV is not set", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(venvProcess.ExecuteCall.Receives.Options.WithGroups).To(Equal([]string{"docs", "lint"}))
+					Expect(venvProcess.ExecuteCall.Receives.Options.WithoutGroups).To(Equal([]string{"dev"}))
+				})
+
+				context("when BP_POETRY_INSTALL_DEV is set", func() {
+					it.Before(func() {
+						Expect(os.Setenv("BP_POETRY_INSTALL_DEV", "true")).To(Succeed())
+					})
+
+					it.After(func() {
+						Expect(os.Unsetenv("BP_POETRY_INSTALL_DEV")).To(Succeed())
+					})
+
+					it("selects the dev group", func() {
+						_, err := build(packit.BuildContext{
+							CNBPath:    cnbDir,
+							WorkingDir: workingDir,
+							Plan: packit.BuildpackPlan{
+								Entries: []packit.BuildpackPlanEntry{
+									{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+									{Name: "poetry-venv"},
+								},
+							},
+							Layers: packit.Layers{Path: layersDir},
+							Stack:  "some-stack",
+						})
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(venvProcess.ExecuteCall.Receives.Options.WithGroups).To(Equal([]string{"docs", "lint", "dev"}))
+						Expect(venvProcess.ExecuteCall.Receives.Options.WithoutGroups).To(BeEmpty())
+					})
+				})
+			})
+
+			context("when the poetry version cannot select groups", func() {
+				it.Before(func() {
+					dependencyManager.ResolveCall.Returns.Dependency.Version = "1.1.6"
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError("invalid BP_POETRY_INSTALL_GROUPS or BP_POETRY_WITHOUT_GROUPS: selecting dependency groups requires poetry 1.2 or later, but poetry 1.1.6 is installed, set BP_POETRY_VERSION to a later version"))
+					Expect(venvProcess.ExecuteCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when the venv layer was installed with a different selection", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(layersDir, "poetry-venv.toml"), []byte(`[metadata]
+lock-sha = "some-lock-sha"
+python-version = "1.23.4"
+poetry-version = "1.2.0"
+dev = false
+with-groups = "docs"
+without-groups = "dev"
+extras = "postgres"
+`), 0600)).To(Succeed())
+				})
+
+				it("reinstalls the dependencies", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(venvProcess.ExecuteCall.CallCount).To(Equal(1))
+				})
+			})
+		})
+
+		context("failure cases", func() {
+			context("when BP_POETRY_INSTALL_DEV is not a boolean", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_POETRY_INSTALL_DEV", "not-a-bool")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_POETRY_INSTALL_DEV")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError(ContainSubstring(`failed to parse BP_POETRY_INSTALL_DEV value "not-a-bool"`)))
+				})
+			})
+
+			context("when BP_POETRY_INSTALL_GROUPS names a group that is not declared", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_POETRY_INSTALL_GROUPS", "docs,missing-group")).To(Succeed())
+
+					projectParser.ParseCall.Returns.ProjectMetadata = poetry.ProjectMetadata{
+						Detected: true,
+						Groups:   []string{"main", "dev", "docs"},
+					}
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_POETRY_INSTALL_GROUPS")).To(Succeed())
+				})
+
+				it("returns an error listing the declared groups", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError(`invalid BP_POETRY_INSTALL_GROUPS: group "missing-group" is not declared in pyproject.toml, valid groups are: [main, dev, docs]`))
+					Expect(venvProcess.ExecuteCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when BP_POETRY_EXTRAS names an extra that is not declared", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_POETRY_EXTRAS", "missing-extra")).To(Succeed())
+
+					projectParser.ParseCall.Returns.ProjectMetadata = poetry.ProjectMetadata{
+						Detected: true,
+						Extras:   []string{"postgres", "mysql"},
+					}
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_POETRY_EXTRAS")).To(Succeed())
+				})
+
+				it("returns an error listing the declared extras", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError(`invalid BP_POETRY_EXTRAS: extra "missing-extra" is not declared in pyproject.toml, valid extras are: [postgres, mysql]`))
+				})
+			})
+
+			context("when the poetry.lock checksum cannot be calculated", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "poetry.lock"), []byte("[metadata]\nlock-version = \"1.1\"\n"), 0600)).To(Succeed())
+					calculator.SumCall.Returns.Error = errors.New("failed to calculate checksum")
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError("failed to calculate checksum of poetry.lock: failed to calculate checksum"))
+				})
+			})
+
+			context("when BP_POETRY_DEFAULT_SCRIPT does not name a script", func() {
+				it.Before(func() {
+					projectParser.ParseCall.Returns.ProjectMetadata.Scripts = []string{"some-script", "other-script"}
+					Expect(os.Setenv("BP_POETRY_DEFAULT_SCRIPT", "missing-script")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_POETRY_DEFAULT_SCRIPT")).To(Succeed())
+				})
+
+				it("returns an error listing the scripts", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError(`failed to assign default process: BP_POETRY_DEFAULT_SCRIPT "missing-script" is not one of the scripts in [tool.poetry.scripts]: [some-script, other-script]`))
+				})
+			})
+
+			context("when a script that is not the default is named web", func() {
+				it.Before(func() {
+					projectParser.ParseCall.Returns.ProjectMetadata.Scripts = []string{"some-script", "web"}
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError(`failed to assign default process: the script "web" conflicts with the web process of the default script "some-script", set BP_POETRY_DEFAULT_SCRIPT=web to make it the default`))
+				})
+			})
+
+			context("when the pyproject.toml cannot be parsed", func() {
+				it.Before(func() {
+					projectParser.ParseCall.Returns.Error = errors.New("failed to parse pyproject.toml")
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError("failed to parse pyproject.toml"))
+				})
+			})
+
+			context("when BP_POETRY_PROJECT_PATH is invalid", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "missing-project")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_POETRY_PROJECT_PATH")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError(`invalid BP_POETRY_PROJECT_PATH "missing-project": missing-project/pyproject.toml does not exist`))
+				})
+			})
+
+			context("when the venv install process fails", func() {
+				it.Before(func() {
+					venvProcess.ExecuteCall.Stub = nil
+					venvProcess.ExecuteCall.Returns.Error = errors.New("failed to install dependencies")
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError("failed to install dependencies"))
+				})
+			})
+
+			context("when the SBOM of the venv layer cannot be generated", func() {
+				it.Before(func() {
+					sbomGenerator.GenerateCall.Stub = func(path string) (sbom.SBOM, error) {
+						if strings.HasPrefix(path, filepath.Join(layersDir, poetry.PoetryVenv)) {
+							return sbom.SBOM{}, errors.New("failed to write cdx SBOM")
+						}
+						return sbom.SBOM{}, nil
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "poetry-venv"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError("failed to generate SBOM for poetry-venv layer: failed to write cdx SBOM"))
+				})
+			})
+		})
+	})
+
+	context("when the buildpack plan requires requirements", func() {
+		var workingDir string
+
+		it.Before(func() {
+			var err error
+			workingDir, err = os.MkdirTemp("", "working-dir")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(workingDir)).To(Succeed())
+		})
+
+		it("exports the application's dependencies into a requirements layer", func() {
+			result, err := build(packit.BuildContext{
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+						{Name: "requirements"},
+					},
+				},
+				Layers: packit.Layers{Path: layersDir},
+				Stack:  "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(3))
+
+			layer := result.Layers[1]
+			Expect(layer.Name).To(Equal("poetry-requirements"))
+			Expect(layer.Path).To(Equal(filepath.Join(layersDir, "poetry-requirements")))
+			Expect(layer.BuildEnv).To(Equal(packit.Environment{
+				"BP_PIP_REQUIREMENT.default": filepath.Join(layersDir, "poetry-requirements", "requirements.txt"),
+			}))
+			Expect(layer.Build).To(BeTrue())
+			Expect(layer.Launch).To(BeFalse())
+			Expect(layer.Cache).To(BeFalse())
+
+			Expect(exportProcess.ExecuteCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(exportProcess.ExecuteCall.Receives.OutputPath).To(Equal(filepath.Join(layersDir, "poetry-requirements", "requirements.txt")))
+			Expect(exportProcess.ExecuteCall.Receives.Options.WithoutHashes).To(BeFalse())
+			Expect(exportProcess.ExecuteCall.Receives.Options.Env).To(ContainElement(MatchRegexp(`^PATH=%s:`, filepath.Join(layersDir, "poetry", "bin"))))
+			Expect(venvProcess.ExecuteCall.CallCount).To(Equal(0))
+
+			Expect(buffer.String()).To(ContainSubstring("Exporting application dependencies"))
+			Expect(buffer.String()).To(MatchRegexp(`BP_PIP_REQUIREMENT\s+-> "%s"`, regexp.QuoteMeta(filepath.Join(layersDir, "poetry-requirements", "requirements.txt"))))
+		})
+
+		context("when BP_POETRY_EXPORT_WITHOUT_HASHES and a dependency selection are set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_EXPORT_WITHOUT_HASHES", "true")).To(Succeed())
+				Expect(os.Setenv("BP_POETRY_INSTALL_DEV", "true")).To(Succeed())
+				Expect(os.Setenv("BP_POETRY_EXTRAS", "postgres")).To(Succeed())
+
+				projectParser.ParseCall.Returns.ProjectMetadata = poetry.ProjectMetadata{
+					Detected: true,
+					Extras:   []string{"postgres"},
+				}
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_POETRY_EXPORT_WITHOUT_HASHES")).To(Succeed())
+				Expect(os.Unsetenv("BP_POETRY_INSTALL_DEV")).To(Succeed())
+				Expect(os.Unsetenv("BP_POETRY_EXTRAS")).To(Succeed())
+			})
+
+			it("exports the selected dependencies without hashes", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+							{Name: "requirements"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(exportProcess.ExecuteCall.Receives.Options.WithoutHashes).To(BeTrue())
+				Expect(exportProcess.ExecuteCall.Receives.Options.Dev).To(BeTrue())
+				Expect(exportProcess.ExecuteCall.Receives.Options.Extras).To(Equal([]string{"postgres"}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when BP_POETRY_EXPORT_WITHOUT_HASHES is not a boolean", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_POETRY_EXPORT_WITHOUT_HASHES", "not-a-bool")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_POETRY_EXPORT_WITHOUT_HASHES")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "requirements"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError(ContainSubstring(`failed to parse BP_POETRY_EXPORT_WITHOUT_HASHES value "not-a-bool"`)))
+				})
+			})
+
+			context("when the export process fails", func() {
+				it.Before(func() {
+					exportProcess.ExecuteCall.Returns.Error = errors.New("failed to export")
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						CNBPath:    cnbDir,
+						WorkingDir: workingDir,
+						Plan: packit.BuildpackPlan{
+							Entries: []packit.BuildpackPlanEntry{
+								{Name: "poetry", Metadata: map[string]interface{}{"build": true}},
+								{Name: "requirements"},
+							},
+						},
+						Layers: packit.Layers{Path: layersDir},
+						Stack:  "some-stack",
+					})
+					Expect(err).To(MatchError("failed to export"))
+				})
+			})
+		})
+	})
+
+	context("when a buildpack plan entry requests a poetry version", func() {
+		it.Before(func() {
+			entryResolver.ResolveCall.Returns.BuildpackPlanEntry = packit.BuildpackPlanEntry{
+				Name: "poetry",
+				Metadata: map[string]interface{}{
+					"version":        "1.1.*",
+					"version-source": "BP_POETRY_VERSION",
+				},
+			}
+		})
+
+		it("resolves the requested poetry version", func() {
+			_, err := build(packit.BuildContext{
+				CNBPath: cnbDir,
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{
+							Name: "poetry",
+							Metadata: map[string]interface{}{
+								"version":        "1.1.*",
+								"version-source": "BP_POETRY_VERSION",
+							},
+						},
+						{
+							Name: "poetry",
+							Metadata: map[string]interface{}{
+								"version":        "1.0.*",
+								"version-source": "some-buildpack",
+							},
+						},
+					},
+				},
+				Layers: packit.Layers{Path: layersDir},
+				Stack:  "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("1.1.*"))
+			Expect(buffer.String()).To(ContainSubstring("Selected poetry-dependency-name version (using BP_POETRY_VERSION): poetry-dependency-version"))
+		})
+
+		context("when the entries are resolved by the draft planner", func() {
+			var entries []packit.BuildpackPlanEntry
+
+			it.Before(func() {
+				build = poetry.Build(dependencyManager, draft.NewPlanner(), installProcess, verifyProcess, pythonVersion, venvProcess, exportProcess, calculator, sbomGenerator, projectParser, scribe.NewEmitter(buffer), chronos.DefaultClock)
+
+				entries = []packit.BuildpackPlanEntry{
+					{
+						Name:     "poetry",
+						Metadata: map[string]interface{}{"build": true},
+					},
+					{
+						Name: "poetry",
+						Metadata: map[string]interface{}{
+							"version":        "3.*",
+							"version-source": "other-buildpack",
+						},
+					},
+					{
+						Name: "poetry",
+						Metadata: map[string]interface{}{
+							"version":        "2.*",
+							"version-source": "pyproject.toml",
+						},
+					},
+					{
+						Name: "poetry",
+						Metadata: map[string]interface{}{
+							"version":        "1.*",
+							"version-source": "BP_POETRY_VERSION",
+						},
+					},
+				}
+			})
+
+			it("prefers BP_POETRY_VERSION over the pyproject.toml and other buildpacks", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan:    packit.BuildpackPlan{Entries: entries},
+					Layers:  packit.Layers{Path: layersDir},
+					Stack:   "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("1.*"))
+			})
+
+			it("prefers the pyproject.toml over other buildpacks", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan:    packit.BuildpackPlan{Entries: entries[:3]},
+					Layers:  packit.Layers{Path: layersDir},
+					Stack:   "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("2.*"))
+			})
+
+			it("prefers the version of another buildpack over the default version", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan:    packit.BuildpackPlan{Entries: entries[:2]},
+					Layers:  packit.Layers{Path: layersDir},
+					Stack:   "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dependencyManager.ResolveCall.Receives.Version).To(Equal("3.*"))
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the poetry dependency cannot be resolved", func() {
+			it.Before(func() {
+				dependencyManager.ResolveCall.Returns.Error = errors.New(`failed to satisfy "poetry" dependency version constraint "9.9.9": no compatible versions. Supported versions are: [1.1.6]`)
+			})
+
+			it("returns an error listing the supported versions", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to resolve poetry dependency:")))
+				Expect(err).To(MatchError(ContainSubstring("Supported versions are: [1.1.6]")))
+			})
+		})
+
+		context("when the poetry layer cannot be retrieved", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(layersDir, "poetry.toml"), []byte("%%%"), 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to get poetry layer:")))
+			})
+		})
+
+		context("when the python version cannot be determined", func() {
+			it.Before(func() {
+				pythonVersion.ExecuteCall.Returns.Error = errors.New("failed to determine python version")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError("failed to determine python version"))
+			})
+		})
+
+		context("when the poetry-source dir cannot be created", func() {
+			var tmpDir string
+
+			it.Before(func() {
+				tmpDir = os.Getenv("TMPDIR")
+				Expect(os.Setenv("TMPDIR", "/no/such/dir")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Setenv("TMPDIR", tmpDir)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to create temp poetry-source dir:")))
+			})
+		})
+
+		context("when the poetry dependency cannot be delivered", func() {
+			it.Before(func() {
+				dependencyManager.DeliverCall.Returns.Error = errors.New("failed to fetch dependency")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError("failed to deliver poetry dependency: failed to fetch dependency"))
+			})
+		})
+
+		context("when poetry cannot be installed", func() {
+			it.Before(func() {
+				installProcess.ExecuteCall.Stub = nil
+				installProcess.ExecuteCall.Returns.Error = errors.New("failed to configure poetry")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError("failed to install poetry: failed to configure poetry"))
+			})
+		})
+
+		context("when the installed poetry cannot be verified", func() {
+			it.Before(func() {
+				verifyProcess.ExecuteCall.Returns.Error = errors.New("installed poetry does not report version poetry-dependency-version")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError("failed to verify poetry: installed poetry does not report version poetry-dependency-version"))
+			})
+		})
+
+		context("when the SBOM of the poetry layer cannot be generated", func() {
+			it.Before(func() {
+				sbomGenerator.GenerateCall.Returns.Error = errors.New("failed to write cdx SBOM")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					CNBPath: cnbDir,
+					Plan: packit.BuildpackPlan{
+						Entries: []packit.BuildpackPlanEntry{
+							{Name: "poetry"},
+						},
+					},
+					Layers: packit.Layers{Path: layersDir},
+					Stack:  "some-stack",
+				})
+				Expect(err).To(MatchError("failed to generate SBOM for poetry layer: failed to write cdx SBOM"))
+			})
 		})
 	})
 }
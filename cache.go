@@ -0,0 +1,82 @@
+package poetry
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// PruneCache removes the files within path that were last modified more than
+// maxAge before now, and then the least recently modified of the remaining
+// files until their total size is at most maxSize bytes. It returns the number
+// of files removed.
+//
+// Pip and poetry never rewrite a cached file when they use it, so maxAge is a
+// time-to-live rather than a limit on how long a file may go unused: a file
+// that every build uses is still removed, and downloaded again, once it is
+// older than maxAge. Likewise, the size limit removes the oldest downloads
+// first, not the least recently used ones. Access times are not used instead,
+// since they are often not recorded by the filesystems that builds run on.
+func PruneCache(path string, maxAge time.Duration, maxSize int64, now time.Time) (int, error) {
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var (
+		files []cacheFile
+		size  int64
+	)
+
+	err := filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		size += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	var removed int
+	for _, file := range files {
+		if now.Sub(file.modTime) <= maxAge && size <= maxSize {
+			break
+		}
+
+		err = os.Remove(file.path)
+		if err != nil {
+			return removed, fmt.Errorf("failed to prune cache: %w", err)
+		}
+
+		size -= file.size
+		removed++
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,88 @@
+package poetry_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paketo-community/poetry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPruneCache(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		cacheDir string
+		now      time.Time
+	)
+
+	writeFile := func(name string, size int, age time.Duration) {
+		path := filepath.Join(cacheDir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+		Expect(ioutil.WriteFile(path, make([]byte, size), 0644)).To(Succeed())
+		Expect(os.Chtimes(path, now.Add(-age), now.Add(-age))).To(Succeed())
+	}
+
+	it.Before(func() {
+		var err error
+		cacheDir, err = ioutil.TempDir("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		now = time.Now()
+
+		writeFile(filepath.Join("pip", "http", "new-wheel"), 10, time.Hour)
+		writeFile(filepath.Join("pip", "http", "old-wheel"), 10, 48*time.Hour)
+		writeFile(filepath.Join("artifacts", "newer-wheel"), 10, time.Minute)
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	context("when the files are within the limits", func() {
+		it("keeps them", func() {
+			removed, err := poetry.PruneCache(cacheDir, 72*time.Hour, 30, now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(Equal(0))
+
+			Expect(filepath.Join(cacheDir, "pip", "http", "old-wheel")).To(BeAnExistingFile())
+		})
+	})
+
+	context("when files are older than the maximum age", func() {
+		it("removes them", func() {
+			removed, err := poetry.PruneCache(cacheDir, 24*time.Hour, 30, now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(Equal(1))
+
+			Expect(filepath.Join(cacheDir, "pip", "http", "old-wheel")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(cacheDir, "pip", "http", "new-wheel")).To(BeAnExistingFile())
+			Expect(filepath.Join(cacheDir, "artifacts", "newer-wheel")).To(BeAnExistingFile())
+		})
+	})
+
+	context("when the files exceed the maximum size", func() {
+		it("removes the least recently modified files", func() {
+			removed, err := poetry.PruneCache(cacheDir, 72*time.Hour, 15, now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(Equal(2))
+
+			Expect(filepath.Join(cacheDir, "pip", "http", "old-wheel")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(cacheDir, "pip", "http", "new-wheel")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(cacheDir, "artifacts", "newer-wheel")).To(BeAnExistingFile())
+		})
+	})
+
+	context("when the cache does not exist", func() {
+		it("does nothing", func() {
+			removed, err := poetry.PruneCache(filepath.Join(cacheDir, "missing"), time.Hour, 0, now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(Equal(0))
+		})
+	})
+}
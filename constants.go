@@ -1,8 +1,26 @@
 package poetry
 
 const (
-	Poetry    = "poetry"
-	Pip       = "pip"
-	CPython   = "cpython"
-	PyProject = "pyproject.toml"
+	Poetry             = "poetry"
+	Pip                = "pip"
+	CPython            = "cpython"
+	PoetryVenv         = "poetry-venv"
+	PoetryCache        = "poetry-cache"
+	PoetryRequirements = "poetry-requirements"
+	Requirements       = "requirements"
+	PyProject          = "pyproject.toml"
+	PoetryLock         = "poetry.lock"
+
+	DependencySHAKey = "dependency-sha"
+	VersionKey       = "version"
+	StackKey         = "stack"
+	PythonVersionKey = "python-version"
+	PoetryVersionKey = "poetry-version"
+	LockSHAKey       = "lock-sha"
+	DevKey           = "dev"
+	WithGroupsKey    = "with-groups"
+	WithoutGroupsKey = "without-groups"
+	ExtrasKey        = "extras"
+	VenvPathKey      = "venv-path"
+	VenvKey          = "venv"
 )
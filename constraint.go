@@ -0,0 +1,302 @@
+package poetry
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+var (
+	constraintOperatorSpacing = regexp.MustCompile(`(===|==|!=|~=|<=|>=|<|>|=|\^|~)\s+`)
+	constraintSeparator       = regexp.MustCompile(`[,\s]+`)
+	constraintAlternative     = regexp.MustCompile(`\|\|?`)
+	constraintComparator      = regexp.MustCompile(`^(===|==|!=|~=|<=|>=|<|>|=|\^|~)?v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(\.\*)?$`)
+)
+
+type constraintBound struct {
+	version   *semver.Version
+	inclusive bool
+}
+
+// constraintGap is a half-open range of versions excluded by a != comparator,
+// from lower up to but not including upper.
+type constraintGap struct {
+	lower *semver.Version
+	upper *semver.Version
+}
+
+// constraintRange is the set of versions matched by a group of comparators
+// that must all be satisfied.
+type constraintRange struct {
+	lower    *constraintBound
+	upper    *constraintBound
+	excluded []string
+	gaps     []constraintGap
+}
+
+// TranslateConstraint converts a poetry or PEP 440 version constraint, such
+// as "^3.8", "~3.7", "~=3.7", ">=3.7,<4.0" or "3.8.*", into an equivalent
+// Masterminds semver constraint. Partial versions in comparisons are padded
+// with zeros, as in PEP 440, while an exact partial version such as "3.8"
+// matches every release of that version, as poetry does for python. An error
+// is returned when the constraint is malformed or cannot be satisfied by any
+// version.
+func TranslateConstraint(constraint string) (string, error) {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" {
+		return "", nil
+	}
+
+	var alternatives []string
+	for _, alternative := range constraintAlternative.Split(constraint, -1) {
+		r, err := parseConstraintRange(alternative)
+		if err != nil {
+			return "", fmt.Errorf("failed to translate constraint %q: %w", constraint, err)
+		}
+
+		if !r.satisfiable() {
+			continue
+		}
+
+		alternatives = append(alternatives, r.String())
+	}
+
+	if len(alternatives) == 0 {
+		return "", fmt.Errorf("failed to translate constraint %q: no version can satisfy it", constraint)
+	}
+
+	return strings.Join(alternatives, " || "), nil
+}
+
+func parseConstraintRange(alternative string) (constraintRange, error) {
+	var r constraintRange
+
+	alternative = constraintOperatorSpacing.ReplaceAllString(strings.TrimSpace(alternative), "$1")
+	if alternative == "" {
+		return r, fmt.Errorf("empty alternative")
+	}
+
+	for _, comparator := range constraintSeparator.Split(alternative, -1) {
+		if comparator == "" || comparator == "*" {
+			continue
+		}
+
+		matches := constraintComparator.FindStringSubmatch(comparator)
+		if matches == nil {
+			return r, fmt.Errorf("unsupported comparator %q", comparator)
+		}
+
+		operator, wildcard := matches[1], matches[5] != ""
+
+		var parts []uint64
+		for _, match := range matches[2:5] {
+			if match == "" {
+				break
+			}
+
+			part, err := strconv.ParseUint(match, 10, 64)
+			if err != nil {
+				return r, fmt.Errorf("unsupported comparator %q: %w", comparator, err)
+			}
+			parts = append(parts, part)
+		}
+
+		version := versionFromParts(parts)
+
+		if wildcard && operator != "" && operator != "==" && operator != "=" && operator != "!=" {
+			return r, fmt.Errorf("unsupported comparator %q: wildcards are only allowed with == and !=", comparator)
+		}
+
+		switch operator {
+		case "", "=", "==", "===":
+			if wildcard || len(parts) < 3 {
+				r.restrictLower(constraintBound{version: version, inclusive: true})
+				r.restrictUpper(constraintBound{version: bumpVersion(parts, len(parts)-1)})
+			} else {
+				r.restrictLower(constraintBound{version: version, inclusive: true})
+				r.restrictUpper(constraintBound{version: version, inclusive: true})
+			}
+
+		case "!=":
+			if wildcard || len(parts) < 3 {
+				r.excluded = append(r.excluded, joinParts(parts))
+				r.gaps = append(r.gaps, constraintGap{lower: version, upper: bumpVersion(parts, len(parts)-1)})
+			} else {
+				r.excluded = append(r.excluded, version.String())
+				r.gaps = append(r.gaps, constraintGap{lower: version, upper: bumpVersion(parts, 2)})
+			}
+
+		case ">=":
+			r.restrictLower(constraintBound{version: version, inclusive: true})
+
+		case ">":
+			r.restrictLower(constraintBound{version: version})
+
+		case "<=":
+			r.restrictUpper(constraintBound{version: version, inclusive: true})
+
+		case "<":
+			r.restrictUpper(constraintBound{version: version})
+
+		case "^":
+			// The upper bound increments the left-most non-zero part, or the last
+			// given part when every part is zero.
+			index := len(parts) - 1
+			for i, part := range parts {
+				if part != 0 {
+					index = i
+					break
+				}
+			}
+
+			r.restrictLower(constraintBound{version: version, inclusive: true})
+			r.restrictUpper(constraintBound{version: bumpVersion(parts, index)})
+
+		case "~":
+			index := 1
+			if len(parts) == 1 {
+				index = 0
+			}
+
+			r.restrictLower(constraintBound{version: version, inclusive: true})
+			r.restrictUpper(constraintBound{version: bumpVersion(parts, index)})
+
+		case "~=":
+			if len(parts) < 2 {
+				return r, fmt.Errorf("unsupported comparator %q: compatible release requires at least two version parts", comparator)
+			}
+
+			r.restrictLower(constraintBound{version: version, inclusive: true})
+			r.restrictUpper(constraintBound{version: bumpVersion(parts, len(parts)-2)})
+		}
+	}
+
+	return r, nil
+}
+
+func (r *constraintRange) restrictLower(bound constraintBound) {
+	if r.lower == nil {
+		r.lower = &bound
+		return
+	}
+
+	switch comparison := bound.version.Compare(r.lower.version); {
+	case comparison > 0:
+		r.lower = &bound
+	case comparison == 0 && !bound.inclusive:
+		r.lower.inclusive = false
+	}
+}
+
+func (r *constraintRange) restrictUpper(bound constraintBound) {
+	if r.upper == nil {
+		r.upper = &bound
+		return
+	}
+
+	switch comparison := bound.version.Compare(r.upper.version); {
+	case comparison < 0:
+		r.upper = &bound
+	case comparison == 0 && !bound.inclusive:
+		r.upper.inclusive = false
+	}
+}
+
+// satisfiable reports whether any release version lies within the bounds of
+// the range and outside all of its exclusions. Release versions are discrete
+// at the patch level, so the lowest candidate is the lower bound, or the patch
+// release after it when it is exclusive, and it is moved past every exclusion
+// that contains it before being compared with the upper bound.
+func (r constraintRange) satisfiable() bool {
+	if r.upper == nil {
+		return true
+	}
+
+	candidate := semver.MustParse("0.0.0")
+	if r.lower != nil {
+		candidate = r.lower.version
+		if !r.lower.inclusive {
+			next := candidate.IncPatch()
+			candidate = &next
+		}
+	}
+
+	for moved := true; moved; {
+		moved = false
+		for _, gap := range r.gaps {
+			if !candidate.LessThan(gap.lower) && candidate.LessThan(gap.upper) {
+				candidate = gap.upper
+				moved = true
+			}
+		}
+	}
+
+	if r.upper.inclusive {
+		return !candidate.GreaterThan(r.upper.version)
+	}
+
+	return candidate.LessThan(r.upper.version)
+}
+
+func (r constraintRange) String() string {
+	var comparators []string
+
+	switch {
+	case r.lower != nil && r.upper != nil && r.lower.version.Equal(r.upper.version):
+		comparators = append(comparators, "="+r.lower.version.String())
+
+	default:
+		if r.lower != nil {
+			operator := ">"
+			if r.lower.inclusive {
+				operator = ">="
+			}
+			comparators = append(comparators, operator+r.lower.version.String())
+		}
+
+		if r.upper != nil {
+			operator := "<"
+			if r.upper.inclusive {
+				operator = "<="
+			}
+			comparators = append(comparators, operator+r.upper.version.String())
+		}
+	}
+
+	for _, excluded := range r.excluded {
+		comparators = append(comparators, "!="+excluded)
+	}
+
+	if len(comparators) == 0 {
+		return "*"
+	}
+
+	return strings.Join(comparators, ", ")
+}
+
+func versionFromParts(parts []uint64) *semver.Version {
+	padded := make([]uint64, 3)
+	copy(padded, parts)
+
+	return semver.MustParse(fmt.Sprintf("%d.%d.%d", padded[0], padded[1], padded[2]))
+}
+
+func bumpVersion(parts []uint64, index int) *semver.Version {
+	bumped := make([]uint64, 3)
+	copy(bumped, parts[:index])
+	bumped[index] = parts[index] + 1
+
+	return versionFromParts(bumped)
+}
+
+func joinParts(parts []uint64) string {
+	var values []string
+	for _, part := range parts {
+		values = append(values, strconv.FormatUint(part, 10))
+	}
+
+	return strings.Join(values, ".")
+}
@@ -0,0 +1,93 @@
+package poetry_test
+
+import (
+	"testing"
+
+	"github.com/paketo-community/poetry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testTranslateConstraint(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	it("translates poetry and PEP 440 constraints into semver constraints", func() {
+		for _, c := range []struct {
+			constraint string
+			expected   string
+		}{
+			{"", ""},
+			{"*", "*"},
+			{"3.8", ">=3.8.0, <3.9.0"},
+			{"3.8.1", "=3.8.1"},
+			{"==3.8.1", "=3.8.1"},
+			{"3.8.*", ">=3.8.0, <3.9.0"},
+			{"==3.*", ">=3.0.0, <4.0.0"},
+			{"^3.8", ">=3.8.0, <4.0.0"},
+			{"^3.8.1", ">=3.8.1, <4.0.0"},
+			{"^0.2.3", ">=0.2.3, <0.3.0"},
+			{"^0.0.3", ">=0.0.3, <0.0.4"},
+			{"^0.0", ">=0.0.0, <0.1.0"},
+			{"^0", ">=0.0.0, <1.0.0"},
+			{"~3.7", ">=3.7.0, <3.8.0"},
+			{"~3.7.2", ">=3.7.2, <3.8.0"},
+			{"~3", ">=3.0.0, <4.0.0"},
+			{"~=3.7", ">=3.7.0, <4.0.0"},
+			{"~=3.7.1", ">=3.7.1, <3.8.0"},
+			{">=3.7,<4.0", ">=3.7.0, <4.0.0"},
+			{">= 3.7, < 4.0", ">=3.7.0, <4.0.0"},
+			{">=3.7 <4.0", ">=3.7.0, <4.0.0"},
+			{">3.8", ">3.8.0"},
+			{"<=3.8", "<=3.8.0"},
+			{">=3.6,!=3.7.0", ">=3.6.0, !=3.7.0"},
+			{">=3.6,!=3.7.*", ">=3.6.0, !=3.7"},
+			{">=3.6,<3.9,!=3.6.*,!=3.7.*", ">=3.6.0, <3.9.0, !=3.6, !=3.7"},
+			{">3.7.0,<=3.7.2,!=3.7.1", ">3.7.0, <=3.7.2, !=3.7.1"},
+			{">=3.8,<=3.8", "=3.8.0"},
+			{"^2.7 || ^3.6", ">=2.7.0, <3.0.0 || >=3.6.0, <4.0.0"},
+			{"~2.7 | >=3.6", ">=2.7.0, <2.8.0 || >=3.6.0"},
+			{">=3.9,<3.8 || ^3.6", ">=3.6.0, <4.0.0"},
+		} {
+			translated, err := poetry.TranslateConstraint(c.constraint)
+			Expect(err).NotTo(HaveOccurred(), c.constraint)
+			Expect(translated).To(Equal(c.expected), c.constraint)
+		}
+	})
+
+	context("failure cases", func() {
+		it("rejects unsatisfiable constraints", func() {
+			for _, constraint := range []string{
+				">=3.9,<3.8",
+				">3.8.0,<=3.8.0",
+				"3.8.1,!=3.8.1",
+				"3.7.*,!=3.7.*",
+				">=3.6,<3.7,!=3.6.*",
+				">=3.6,<3.8,!=3.6.*,!=3.7.*",
+				">3.7.0,<=3.7.1,!=3.7.1",
+				"^3.0,!=3",
+				"^3.8,<3.0 || ~2.7,>=3.0",
+			} {
+				_, err := poetry.TranslateConstraint(constraint)
+				Expect(err).To(MatchError(ContainSubstring("no version can satisfy it")), constraint)
+			}
+		})
+
+		it("rejects malformed constraints", func() {
+			for _, c := range []struct {
+				constraint string
+				message    string
+			}{
+				{"three", `unsupported comparator "three"`},
+				{"3.8.0rc1", `unsupported comparator "3.8.0rc1"`},
+				{"3.8.0.1", `unsupported comparator "3.8.0.1"`},
+				{">=3.*", "wildcards are only allowed with == and !="},
+				{"~=3", "compatible release requires at least two version parts"},
+				{"^3.8 ||", "empty alternative"},
+			} {
+				_, err := poetry.TranslateConstraint(c.constraint)
+				Expect(err).To(MatchError(ContainSubstring(c.message)), c.constraint)
+			}
+		})
+	})
+}
@@ -1,60 +1,177 @@
 package poetry
 
-import "github.com/paketo-buildpacks/packit"
+import (
+	"os"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
 
 //go:generate faux --interface ProjectParser --output fakes/project_parser.go
 type ProjectParser interface {
-	Parse(path string) (detected bool, version string, err error)
+	Parse(path string) (ProjectMetadata, error)
+}
+
+// ProjectMetadata is the poetry configuration found in a pyproject.toml.
+type ProjectMetadata struct {
+	// Detected indicates whether the project is managed by poetry.
+	Detected bool
+
+	// DetectionReason describes why the project was detected as being managed
+	// by poetry.
+	DetectionReason string
+
+	// PythonVersion is the python version constraint of the project.
+	PythonVersion string
+
+	// PythonVersionSource identifies the pyproject.toml table that the python
+	// version constraint was read from.
+	PythonVersionSource string
+
+	// PoetryVersion is the poetry version constraint of the project, as
+	// declared in [build-system] requires.
+	PoetryVersion string
+
+	// Scripts are the names of the entries in [tool.poetry.scripts], in the
+	// order they are declared.
+	Scripts []string
+
+	// Groups are the names of the dependency groups of the project, including
+	// the implicit main group and the dev group of [tool.poetry.dev-dependencies].
+	Groups []string
+
+	// Extras are the names of the extras declared in [tool.poetry.extras] or
+	// [project.optional-dependencies].
+	Extras []string
 }
 
 type BuildPlanMetadata struct {
 	Version       string `toml:"version,omitempty"`
 	VersionSource string `toml:"version-source,omitempty"`
 	Build         bool   `toml:"build"`
+	Launch        bool   `toml:"launch,omitempty"`
 }
 
-func Detect(pyProjParser ProjectParser) packit.DetectFunc {
+func Detect(pyProjParser ProjectParser, logger scribe.Emitter) packit.DetectFunc {
 	return func(context packit.DetectContext) (packit.DetectResult, error) {
+		launch, err := parseBoolEnv("BP_POETRY_LAUNCH")
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
 
 		pythonRequirement := packit.BuildPlanRequirement{
 			Name: "cpython",
 			Metadata: BuildPlanMetadata{
-				Build: true,
+				Build:  true,
+				Launch: launch,
 			},
 		}
 
-		detected, pythonVersion, err := pyProjParser.Parse(context.WorkingDir)
+		projectPath, err := ProjectPath(context.WorkingDir)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		project, err := pyProjParser.Parse(projectPath)
 		if err != nil {
-			panic(err)
+			return packit.DetectResult{}, err
 		}
 
-		if !detected {
+		if !project.Detected {
 			return packit.DetectResult{}, packit.Fail
 		}
 
-		if pythonVersion != "" {
+		logger.Process("Detected poetry project: %s", project.DetectionReason)
+
+		if project.PythonVersion != "" {
 			pythonRequirement = packit.BuildPlanRequirement{
 				Name: "cpython",
 				Metadata: BuildPlanMetadata{
-					Version:       pythonVersion,
-					VersionSource: "pyproject.toml",
+					Version:       project.PythonVersion,
+					VersionSource: project.PythonVersionSource,
 					Build:         true,
+					Launch:        launch,
 				},
 			}
 		}
 
+		requirements := []packit.BuildPlanRequirement{
+			pythonRequirement,
+			{
+				Name: Pip,
+				Metadata: BuildPlanMetadata{
+					Build: true,
+				},
+			},
+		}
+
+		if project.PoetryVersion != "" {
+			requirements = append(requirements, packit.BuildPlanRequirement{
+				Name: Poetry,
+				Metadata: BuildPlanMetadata{
+					Version:       project.PoetryVersion,
+					VersionSource: "pyproject.toml",
+				},
+			})
+		}
+
+		if version, ok := os.LookupEnv("BP_POETRY_VERSION"); ok {
+			requirements = append(requirements, packit.BuildPlanRequirement{
+				Name: Poetry,
+				Metadata: BuildPlanMetadata{
+					Version:       version,
+					VersionSource: "BP_POETRY_VERSION",
+				},
+			})
+		}
+
+		// Poetry is only made available at launch, along with the python it runs
+		// on, when the application asks for it, such as for a `poetry run` start
+		// command.
+		if launch {
+			requirements = append(requirements, packit.BuildPlanRequirement{
+				Name: Poetry,
+				Metadata: BuildPlanMetadata{
+					Launch: true,
+				},
+			})
+		}
+
+		// Installing or exporting the application's dependencies needs poetry
+		// during the build even when no other buildpack requires it, and every
+		// provided entry must be required.
+		dependencyRequirements := append([]packit.BuildPlanRequirement{}, requirements...)
+		dependencyRequirements = append(dependencyRequirements, packit.BuildPlanRequirement{
+			Name: Poetry,
+			Metadata: BuildPlanMetadata{
+				Build: true,
+			},
+		})
+
+		// The application's dependencies are only installed into a venv when a
+		// subsequent buildpack requires poetry-venv, or exported to a
+		// requirements file when a subsequent buildpack requires requirements.
+		// Otherwise only poetry itself is provided.
 		return packit.DetectResult{
 			Plan: packit.BuildPlan{
 				Provides: []packit.BuildPlanProvision{
-					{Name: "poetry"},
+					{Name: Poetry},
+					{Name: PoetryVenv},
 				},
-				Requires: []packit.BuildPlanRequirement{
-					pythonRequirement,
+				Requires: dependencyRequirements,
+				Or: []packit.BuildPlan{
+					{
+						Provides: []packit.BuildPlanProvision{
+							{Name: Poetry},
+						},
+						Requires: requirements,
+					},
 					{
-						Name: Pip,
-						Metadata: BuildPlanMetadata{
-							Build: true,
+						Provides: []packit.BuildPlanProvision{
+							{Name: Poetry},
+							{Name: Requirements},
 						},
+						Requires: dependencyRequirements,
 					},
 				},
 			},
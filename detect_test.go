@@ -1,9 +1,14 @@
 package poetry_test
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
-	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 	"github.com/paketo-community/poetry"
 	"github.com/paketo-community/poetry/fakes"
 	"github.com/sclevine/spec"
@@ -16,14 +21,17 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		Expect = NewWithT(t).Expect
 
 		pyProjParser *fakes.ProjectParser
+		buffer       *bytes.Buffer
 		detect       packit.DetectFunc
 	)
 
 	it.Before(func() {
 		pyProjParser = &fakes.ProjectParser{}
-		pyProjParser.ParseCall.Returns.Detected = true
+		pyProjParser.ParseCall.Returns.ProjectMetadata.Detected = true
+		pyProjParser.ParseCall.Returns.ProjectMetadata.DetectionReason = "some-reason"
 
-		detect = poetry.Detect(pyProjParser)
+		buffer = bytes.NewBuffer(nil)
+		detect = poetry.Detect(pyProjParser, scribe.NewEmitter(buffer))
 	})
 
 	it("returns a plan that provides poetry", func() {
@@ -35,6 +43,7 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			Plan: packit.BuildPlan{
 				Provides: []packit.BuildPlanProvision{
 					{Name: poetry.Poetry},
+					{Name: poetry.PoetryVenv},
 				},
 				Requires: []packit.BuildPlanRequirement{
 					{
@@ -49,15 +58,70 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 							Build: true,
 						},
 					},
+					{
+						Name: poetry.Poetry,
+						Metadata: poetry.BuildPlanMetadata{
+							Build: true,
+						},
+					},
+				},
+				Or: []packit.BuildPlan{
+					{
+						Provides: []packit.BuildPlanProvision{
+							{Name: poetry.Poetry},
+						},
+						Requires: []packit.BuildPlanRequirement{
+							{
+								Name: poetry.CPython,
+								Metadata: poetry.BuildPlanMetadata{
+									Build: true,
+								},
+							},
+							{
+								Name: poetry.Pip,
+								Metadata: poetry.BuildPlanMetadata{
+									Build: true,
+								},
+							},
+						},
+					},
+					{
+						Provides: []packit.BuildPlanProvision{
+							{Name: poetry.Poetry},
+							{Name: poetry.Requirements},
+						},
+						Requires: []packit.BuildPlanRequirement{
+							{
+								Name: poetry.CPython,
+								Metadata: poetry.BuildPlanMetadata{
+									Build: true,
+								},
+							},
+							{
+								Name: poetry.Pip,
+								Metadata: poetry.BuildPlanMetadata{
+									Build: true,
+								},
+							},
+							{
+								Name: poetry.Poetry,
+								Metadata: poetry.BuildPlanMetadata{
+									Build: true,
+								},
+							},
+						},
+					},
 				},
 			},
 		}))
 		Expect(pyProjParser.ParseCall.Receives.Path).To(Equal("/working-dir"))
+		Expect(buffer.String()).To(ContainSubstring("Detected poetry project: some-reason"))
 	})
 
 	context("when pyproject.toml provides a Python version", func() {
 		it.Before(func() {
-			pyProjParser.ParseCall.Returns.Version = "3.8"
+			pyProjParser.ParseCall.Returns.ProjectMetadata.PythonVersion = "3.8"
+			pyProjParser.ParseCall.Returns.ProjectMetadata.PythonVersionSource = "pyproject.toml:tool.poetry.dependencies.python"
 		})
 
 		it("returns a plan that provides poetry and requires specific Python version", func() {
@@ -69,13 +133,14 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				Plan: packit.BuildPlan{
 					Provides: []packit.BuildPlanProvision{
 						{Name: "poetry"},
+						{Name: "poetry-venv"},
 					},
 					Requires: []packit.BuildPlanRequirement{
 						{
 							Name: "cpython",
 							Metadata: poetry.BuildPlanMetadata{
 								Version:       "3.8",
-								VersionSource: "pyproject.toml",
+								VersionSource: "pyproject.toml:tool.poetry.dependencies.python",
 								Build:         true,
 							},
 						},
@@ -85,15 +150,159 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 								Build: true,
 							},
 						},
+						{
+							Name: "poetry",
+							Metadata: poetry.BuildPlanMetadata{
+								Build: true,
+							},
+						},
+					},
+					Or: []packit.BuildPlan{
+						{
+							Provides: []packit.BuildPlanProvision{
+								{Name: "poetry"},
+							},
+							Requires: []packit.BuildPlanRequirement{
+								{
+									Name: "cpython",
+									Metadata: poetry.BuildPlanMetadata{
+										Version:       "3.8",
+										VersionSource: "pyproject.toml:tool.poetry.dependencies.python",
+										Build:         true,
+									},
+								},
+								{
+									Name: poetry.Pip,
+									Metadata: poetry.BuildPlanMetadata{
+										Build: true,
+									},
+								},
+							},
+						},
+						{
+							Provides: []packit.BuildPlanProvision{
+								{Name: "poetry"},
+								{Name: "requirements"},
+							},
+							Requires: []packit.BuildPlanRequirement{
+								{
+									Name: "cpython",
+									Metadata: poetry.BuildPlanMetadata{
+										Version:       "3.8",
+										VersionSource: "pyproject.toml:tool.poetry.dependencies.python",
+										Build:         true,
+									},
+								},
+								{
+									Name: poetry.Pip,
+									Metadata: poetry.BuildPlanMetadata{
+										Build: true,
+									},
+								},
+								{
+									Name: "poetry",
+									Metadata: poetry.BuildPlanMetadata{
+										Build: true,
+									},
+								},
+							},
+						},
 					},
 				},
 			}))
 		})
 	})
 
+	context("when pyproject.toml requires a poetry version", func() {
+		it.Before(func() {
+			pyProjParser.ParseCall.Returns.ProjectMetadata.PoetryVersion = ">=1.1,<1.2"
+		})
+
+		it("requires that poetry version", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: "/working-dir",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+				Name: poetry.Poetry,
+				Metadata: poetry.BuildPlanMetadata{
+					Version:       ">=1.1,<1.2",
+					VersionSource: "pyproject.toml",
+				},
+			}))
+		})
+	})
+
+	context("when BP_POETRY_VERSION is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_POETRY_VERSION", "1.1.*")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_POETRY_VERSION")).To(Succeed())
+		})
+
+		it("requires the given poetry version", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: "/working-dir",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+				Name: poetry.Poetry,
+				Metadata: poetry.BuildPlanMetadata{
+					Version:       "1.1.*",
+					VersionSource: "BP_POETRY_VERSION",
+				},
+			}))
+			Expect(result.Plan.Or).To(HaveLen(2))
+			Expect(result.Plan.Or[0].Requires).To(ContainElement(packit.BuildPlanRequirement{
+				Name: poetry.Poetry,
+				Metadata: poetry.BuildPlanMetadata{
+					Version:       "1.1.*",
+					VersionSource: "BP_POETRY_VERSION",
+				},
+			}))
+			Expect(result.Plan.Or[1].Requires).To(ContainElement(packit.BuildPlanRequirement{
+				Name: poetry.Poetry,
+				Metadata: poetry.BuildPlanMetadata{
+					Version:       "1.1.*",
+					VersionSource: "BP_POETRY_VERSION",
+				},
+			}))
+		})
+	})
+
+	context("when BP_POETRY_PROJECT_PATH is set", func() {
+		var workingDir string
+
+		it.Before(func() {
+			var err error
+			workingDir, err = os.MkdirTemp("", "working-dir")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "some-project"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "some-project", "pyproject.toml"), nil, 0644)).To(Succeed())
+
+			Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "some-project")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_POETRY_PROJECT_PATH")).To(Succeed())
+			Expect(os.RemoveAll(workingDir)).To(Succeed())
+		})
+
+		it("parses the pyproject.toml in that directory", func() {
+			_, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pyProjParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "some-project")))
+		})
+	})
+
 	context("when poetry is not detected", func() {
 		it.Before(func() {
-			pyProjParser.ParseCall.Returns.Detected = false
+			pyProjParser.ParseCall.Returns.ProjectMetadata.Detected = false
 		})
 		it("fails detection", func() {
 			result, err := detect(packit.DetectContext{
@@ -103,4 +312,86 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			Expect(result).To(Equal(packit.DetectResult{}))
 		})
 	})
+
+	context("when BP_POETRY_LAUNCH is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_POETRY_LAUNCH", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_POETRY_LAUNCH")).To(Succeed())
+		})
+
+		it("requires poetry and python at launch", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: "/working-dir",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, plan := range append([]packit.BuildPlan{{Requires: result.Plan.Requires}}, result.Plan.Or...) {
+				Expect(plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: poetry.CPython,
+					Metadata: poetry.BuildPlanMetadata{
+						Build:  true,
+						Launch: true,
+					},
+				}))
+				Expect(plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+					Name: poetry.Poetry,
+					Metadata: poetry.BuildPlanMetadata{
+						Launch: true,
+					},
+				}))
+			}
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the pyproject.toml cannot be parsed", func() {
+			it.Before(func() {
+				pyProjParser.ParseCall.Returns.Error = errors.New("failed to parse pyproject.toml")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: "/working-dir",
+				})
+				Expect(err).To(MatchError("failed to parse pyproject.toml"))
+			})
+		})
+
+		context("when BP_POETRY_PROJECT_PATH is invalid", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "../some-project")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_POETRY_PROJECT_PATH")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: "/working-dir",
+				})
+				Expect(err).To(MatchError(ContainSubstring(`invalid BP_POETRY_PROJECT_PATH "../some-project"`)))
+			})
+		})
+
+		context("when BP_POETRY_LAUNCH is not a boolean", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_LAUNCH", "not-a-bool")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_POETRY_LAUNCH")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: "/working-dir",
+				})
+				Expect(err).To(MatchError(ContainSubstring(`failed to parse BP_POETRY_LAUNCH value "not-a-bool"`)))
+			})
+		})
+	})
 }
@@ -3,8 +3,7 @@ package fakes
 import (
 	"sync"
 
-	"github.com/paketo-buildpacks/packit"
-	"github.com/paketo-buildpacks/packit/postal"
+	"github.com/paketo-buildpacks/packit/v2/postal"
 )
 
 type DependencyManager struct {
@@ -22,17 +21,6 @@ type DependencyManager struct {
 		}
 		Stub func(postal.Dependency, string, string, string) error
 	}
-	GenerateBillOfMaterialsCall struct {
-		sync.Mutex
-		CallCount int
-		Receives  struct {
-			Dependencies []postal.Dependency
-		}
-		Returns struct {
-			BOMEntrySlice []packit.BOMEntry
-		}
-		Stub func(...postal.Dependency) []packit.BOMEntry
-	}
 	ResolveCall struct {
 		sync.Mutex
 		CallCount int
@@ -63,16 +51,6 @@ func (f *DependencyManager) Deliver(param1 postal.Dependency, param2 string, par
 	}
 	return f.DeliverCall.Returns.Error
 }
-func (f *DependencyManager) GenerateBillOfMaterials(param1 ...postal.Dependency) []packit.BOMEntry {
-	f.GenerateBillOfMaterialsCall.Lock()
-	defer f.GenerateBillOfMaterialsCall.Unlock()
-	f.GenerateBillOfMaterialsCall.CallCount++
-	f.GenerateBillOfMaterialsCall.Receives.Dependencies = param1
-	if f.GenerateBillOfMaterialsCall.Stub != nil {
-		return f.GenerateBillOfMaterialsCall.Stub(param1...)
-	}
-	return f.GenerateBillOfMaterialsCall.Returns.BOMEntrySlice
-}
 func (f *DependencyManager) Resolve(param1 string, param2 string, param3 string, param4 string) (postal.Dependency, error) {
 	f.ResolveCall.Lock()
 	defer f.ResolveCall.Unlock()
@@ -3,7 +3,7 @@ package fakes
 import (
 	"sync"
 
-	"github.com/paketo-buildpacks/packit"
+	"github.com/paketo-buildpacks/packit/v2"
 )
 
 type EntryResolver struct {
@@ -20,6 +20,20 @@ type EntryResolver struct {
 		}
 		Stub func(string, []packit.BuildpackPlanEntry) (bool, bool)
 	}
+	ResolveCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			Name       string
+			Entries    []packit.BuildpackPlanEntry
+			Priorities []interface{}
+		}
+		Returns struct {
+			BuildpackPlanEntry      packit.BuildpackPlanEntry
+			BuildpackPlanEntrySlice []packit.BuildpackPlanEntry
+		}
+		Stub func(string, []packit.BuildpackPlanEntry, []interface{}) (packit.BuildpackPlanEntry, []packit.BuildpackPlanEntry)
+	}
 }
 
 func (f *EntryResolver) MergeLayerTypes(param1 string, param2 []packit.BuildpackPlanEntry) (bool, bool) {
@@ -33,3 +47,15 @@ func (f *EntryResolver) MergeLayerTypes(param1 string, param2 []packit.Buildpack
 	}
 	return f.MergeLayerTypesCall.Returns.Launch, f.MergeLayerTypesCall.Returns.Build
 }
+func (f *EntryResolver) Resolve(param1 string, param2 []packit.BuildpackPlanEntry, param3 []interface{}) (packit.BuildpackPlanEntry, []packit.BuildpackPlanEntry) {
+	f.ResolveCall.Lock()
+	defer f.ResolveCall.Unlock()
+	f.ResolveCall.CallCount++
+	f.ResolveCall.Receives.Name = param1
+	f.ResolveCall.Receives.Entries = param2
+	f.ResolveCall.Receives.Priorities = param3
+	if f.ResolveCall.Stub != nil {
+		return f.ResolveCall.Stub(param1, param2, param3)
+	}
+	return f.ResolveCall.Returns.BuildpackPlanEntry, f.ResolveCall.Returns.BuildpackPlanEntrySlice
+}
@@ -0,0 +1,36 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-community/poetry"
+)
+
+type ExportProcess struct {
+	ExecuteCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			OutputPath string
+			Options    poetry.ExportOptions
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, string, poetry.ExportOptions) error
+	}
+}
+
+func (f *ExportProcess) Execute(param1 string, param2 string, param3 poetry.ExportOptions) error {
+	f.ExecuteCall.Lock()
+	defer f.ExecuteCall.Unlock()
+	f.ExecuteCall.CallCount++
+	f.ExecuteCall.Receives.WorkingDir = param1
+	f.ExecuteCall.Receives.OutputPath = param2
+	f.ExecuteCall.Receives.Options = param3
+	if f.ExecuteCall.Stub != nil {
+		return f.ExecuteCall.Stub(param1, param2, param3)
+	}
+	return f.ExecuteCall.Returns.Error
+}
@@ -9,22 +9,24 @@ type InstallProcess struct {
 		Receives  struct {
 			SrcPath         string
 			TargetLayerPath string
+			CachePath       string
 		}
 		Returns struct {
 			Error error
 		}
-		Stub func(string, string) error
+		Stub func(string, string, string) error
 	}
 }
 
-func (f *InstallProcess) Execute(param1 string, param2 string) error {
+func (f *InstallProcess) Execute(param1 string, param2 string, param3 string) error {
 	f.ExecuteCall.Lock()
 	defer f.ExecuteCall.Unlock()
 	f.ExecuteCall.CallCount++
 	f.ExecuteCall.Receives.SrcPath = param1
 	f.ExecuteCall.Receives.TargetLayerPath = param2
+	f.ExecuteCall.Receives.CachePath = param3
 	if f.ExecuteCall.Stub != nil {
-		return f.ExecuteCall.Stub(param1, param2)
+		return f.ExecuteCall.Stub(param1, param2, param3)
 	}
 	return f.ExecuteCall.Returns.Error
 }
@@ -1,6 +1,10 @@
 package fakes
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/paketo-community/poetry"
+)
 
 type ProjectParser struct {
 	ParseCall struct {
@@ -10,15 +14,14 @@ type ProjectParser struct {
 			Path string
 		}
 		Returns struct {
-			Detected bool
-			Version  string
-			Err      error
+			ProjectMetadata poetry.ProjectMetadata
+			Error           error
 		}
-		Stub func(string) (bool, string, error)
+		Stub func(string) (poetry.ProjectMetadata, error)
 	}
 }
 
-func (f *ProjectParser) Parse(param1 string) (bool, string, error) {
+func (f *ProjectParser) Parse(param1 string) (poetry.ProjectMetadata, error) {
 	f.ParseCall.Lock()
 	defer f.ParseCall.Unlock()
 	f.ParseCall.CallCount++
@@ -26,5 +29,5 @@ func (f *ProjectParser) Parse(param1 string) (bool, string, error) {
 	if f.ParseCall.Stub != nil {
 		return f.ParseCall.Stub(param1)
 	}
-	return f.ParseCall.Returns.Detected, f.ParseCall.Returns.Version, f.ParseCall.Returns.Err
+	return f.ParseCall.Returns.ProjectMetadata, f.ParseCall.Returns.Error
 }
@@ -2,28 +2,24 @@ package fakes
 
 import "sync"
 
-type SitePackageProcess struct {
+type PythonVersionLookup struct {
 	ExecuteCall struct {
 		sync.Mutex
 		CallCount int
-		Receives  struct {
-			TargetLayerPath string
-		}
-		Returns struct {
+		Returns   struct {
 			String string
 			Error  error
 		}
-		Stub func(string) (string, error)
+		Stub func() (string, error)
 	}
 }
 
-func (f *SitePackageProcess) Execute(param1 string) (string, error) {
+func (f *PythonVersionLookup) Execute() (string, error) {
 	f.ExecuteCall.Lock()
 	defer f.ExecuteCall.Unlock()
 	f.ExecuteCall.CallCount++
-	f.ExecuteCall.Receives.TargetLayerPath = param1
 	if f.ExecuteCall.Stub != nil {
-		return f.ExecuteCall.Stub(param1)
+		return f.ExecuteCall.Stub()
 	}
 	return f.ExecuteCall.Returns.String, f.ExecuteCall.Returns.Error
 }
@@ -0,0 +1,33 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/packit/v2/sbom"
+)
+
+type SBOMGenerator struct {
+	GenerateCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			SBOM  sbom.SBOM
+			Error error
+		}
+		Stub func(string) (sbom.SBOM, error)
+	}
+}
+
+func (f *SBOMGenerator) Generate(param1 string) (sbom.SBOM, error) {
+	f.GenerateCall.Lock()
+	defer f.GenerateCall.Unlock()
+	f.GenerateCall.CallCount++
+	f.GenerateCall.Receives.Path = param1
+	if f.GenerateCall.Stub != nil {
+		return f.GenerateCall.Stub(param1)
+	}
+	return f.GenerateCall.Returns.SBOM, f.GenerateCall.Returns.Error
+}
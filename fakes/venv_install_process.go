@@ -0,0 +1,37 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-community/poetry"
+)
+
+type VenvInstallProcess struct {
+	ExecuteCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir      string
+			TargetLayerPath string
+			Options         poetry.VenvInstallOptions
+		}
+		Returns struct {
+			String string
+			Error  error
+		}
+		Stub func(string, string, poetry.VenvInstallOptions) (string, error)
+	}
+}
+
+func (f *VenvInstallProcess) Execute(param1 string, param2 string, param3 poetry.VenvInstallOptions) (string, error) {
+	f.ExecuteCall.Lock()
+	defer f.ExecuteCall.Unlock()
+	f.ExecuteCall.CallCount++
+	f.ExecuteCall.Receives.WorkingDir = param1
+	f.ExecuteCall.Receives.TargetLayerPath = param2
+	f.ExecuteCall.Receives.Options = param3
+	if f.ExecuteCall.Stub != nil {
+		return f.ExecuteCall.Stub(param1, param2, param3)
+	}
+	return f.ExecuteCall.Returns.String, f.ExecuteCall.Returns.Error
+}
@@ -0,0 +1,30 @@
+package fakes
+
+import "sync"
+
+type VerifyProcess struct {
+	ExecuteCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			LayerPath string
+			Version   string
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, string) error
+	}
+}
+
+func (f *VerifyProcess) Execute(param1 string, param2 string) error {
+	f.ExecuteCall.Lock()
+	defer f.ExecuteCall.Unlock()
+	f.ExecuteCall.CallCount++
+	f.ExecuteCall.Receives.LayerPath = param1
+	f.ExecuteCall.Receives.Version = param2
+	if f.ExecuteCall.Stub != nil {
+		return f.ExecuteCall.Stub(param1, param2)
+	}
+	return f.ExecuteCall.Returns.Error
+}
@@ -3,8 +3,9 @@ module github.com/paketo-community/poetry
 go 1.16
 
 require (
-	github.com/BurntSushi/toml v0.3.1
-	github.com/onsi/gomega v1.11.0
-	github.com/paketo-buildpacks/packit v0.10.2
+	github.com/BurntSushi/toml v1.0.0
+	github.com/Masterminds/semver/v3 v3.1.1
+	github.com/onsi/gomega v1.18.1
+	github.com/paketo-buildpacks/packit/v2 v2.1.0
 	github.com/sclevine/spec v1.4.0
 )
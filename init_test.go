@@ -12,7 +12,16 @@ func TestUnitPoetry(t *testing.T) {
 	suite("Detect", testDetect)
 	suite("Build", testBuild)
 	suite("PyProjParser", testPyProjParser)
+	suite("ProjectPath", testProjectPath)
+	suite("Repositories", testRepositories)
+	suite("PythonSBOMGenerator", testPythonSBOMGenerator)
+	suite("PruneCache", testPruneCache)
+	suite("LockIsStale", testLockIsStale)
+	suite("TranslateConstraint", testTranslateConstraint)
 	suite("InstallProcess", testPoetryInstallProcess)
-	suite("SiteProcess", testSiteProcess)
+	suite("VerifyProcess", testPoetryVerifyProcess)
+	suite("VenvInstallProcess", testPoetryVenvInstallProcess)
+	suite("ExportProcess", testPoetryExportProcess)
+	suite("PythonVersionProcess", testPythonVersionProcess)
 	suite.Run(t)
 }
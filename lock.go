@@ -0,0 +1,246 @@
+package poetry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/BurntSushi/toml"
+)
+
+var (
+	// lockLegacyKeys are the [tool.poetry] keys that poetry always includes in
+	// the content-hash, even when they are absent.
+	lockLegacyKeys = []string{"dependencies", "dev-dependencies", "source", "extras"}
+
+	// lockProjectKeys are the [project] keys that poetry 2 includes in the
+	// content-hash.
+	lockProjectKeys = []string{"requires-python", "dependencies", "optional-dependencies"}
+)
+
+// LockIsStale reports whether the content-hash recorded in the poetry.lock of
+// the project at projectPath differs from the hash of its pyproject.toml,
+// which means that pyproject.toml was changed without running `poetry lock`.
+// Since the sections that poetry hashes have changed between releases, the
+// lock is only stale when it matches none of them. A project without a
+// poetry.lock, or whose poetry.lock records no content-hash, is never stale.
+func LockIsStale(projectPath string) (bool, error) {
+	var lock struct {
+		Metadata struct {
+			ContentHash string `toml:"content-hash"`
+		} `toml:"metadata"`
+	}
+
+	_, err := toml.DecodeFile(filepath.Join(projectPath, PoetryLock), &lock)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to parse %s: %w", PoetryLock, err)
+	}
+
+	if lock.Metadata.ContentHash == "" {
+		return false, nil
+	}
+
+	var pyProject map[string]interface{}
+	_, err = toml.DecodeFile(filepath.Join(projectPath, PyProject), &pyProject)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", PyProject, err)
+	}
+
+	hashes, err := lockContentHashes(pyProject)
+	if err != nil {
+		return false, err
+	}
+
+	for _, hash := range hashes {
+		if hash == lock.Metadata.ContentHash {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// lockContentHashes returns the content-hashes that the releases of poetry
+// would record for the given pyproject.toml content: poetry 1.1 hashes the
+// legacy [tool.poetry] keys, poetry 1.2 adds the dependency groups, and poetry
+// 2 also hashes the dependencies in the [project] table when it has any.
+func lockContentHashes(pyProject map[string]interface{}) ([]string, error) {
+	tool, _ := pyProject["tool"].(map[string]interface{})
+	poetry, _ := tool["poetry"].(map[string]interface{})
+	project, _ := pyProject["project"].(map[string]interface{})
+
+	legacyContent := map[string]interface{}{}
+	for _, key := range lockLegacyKeys {
+		legacyContent[key] = poetry[key]
+	}
+
+	groupContent := map[string]interface{}{}
+	for key, value := range legacyContent {
+		groupContent[key] = value
+	}
+	if group, ok := poetry["group"]; ok {
+		groupContent["group"] = group
+	}
+
+	contents := []interface{}{legacyContent, groupContent}
+
+	projectContent := map[string]interface{}{}
+	for _, key := range lockProjectKeys {
+		if value, ok := project[key]; ok {
+			projectContent[key] = value
+		}
+	}
+
+	if len(projectContent) > 0 {
+		poetryContent := map[string]interface{}{}
+		for _, key := range append(lockLegacyKeys, "group") {
+			if value, ok := poetry[key]; ok {
+				poetryContent[key] = value
+			}
+		}
+
+		contents = append(contents, map[string]interface{}{
+			"project": projectContent,
+			"tool":    map[string]interface{}{"poetry": poetryContent},
+		})
+	}
+
+	var hashes []string
+	for _, content := range contents {
+		var builder strings.Builder
+		err := writePythonJSON(&builder, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate content-hash of %s: %w", PyProject, err)
+		}
+
+		sum := sha256.Sum256([]byte(builder.String()))
+		hashes = append(hashes, hex.EncodeToString(sum[:]))
+	}
+
+	return hashes, nil
+}
+
+// writePythonJSON encodes the value exactly as python's
+// json.dumps(value, sort_keys=True) does, which is what poetry hashes.
+func writePythonJSON(builder *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		builder.WriteString("null")
+
+	case bool:
+		builder.WriteString(strconv.FormatBool(v))
+
+	case int64:
+		builder.WriteString(strconv.FormatInt(v, 10))
+
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			builder.WriteString("NaN")
+		case math.IsInf(v, 1):
+			builder.WriteString("Infinity")
+		case math.IsInf(v, -1):
+			builder.WriteString("-Infinity")
+		default:
+			formatted := strconv.FormatFloat(v, 'g', -1, 64)
+			if !strings.ContainsAny(formatted, ".eEn") {
+				formatted += ".0"
+			}
+			builder.WriteString(formatted)
+		}
+
+	case string:
+		builder.WriteByte('"')
+		for _, r := range v {
+			switch {
+			case r == '"':
+				builder.WriteString(`\"`)
+			case r == '\\':
+				builder.WriteString(`\\`)
+			case r == '\n':
+				builder.WriteString(`\n`)
+			case r == '\r':
+				builder.WriteString(`\r`)
+			case r == '\t':
+				builder.WriteString(`\t`)
+			case r == '\b':
+				builder.WriteString(`\b`)
+			case r == '\f':
+				builder.WriteString(`\f`)
+			case r < 0x20 || r > 0x7e:
+				for _, unit := range utf16.Encode([]rune{r}) {
+					fmt.Fprintf(builder, `\u%04x`, unit)
+				}
+			default:
+				builder.WriteRune(r)
+			}
+		}
+		builder.WriteByte('"')
+
+	case []interface{}:
+		builder.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+
+			err := writePythonJSON(builder, item)
+			if err != nil {
+				return err
+			}
+		}
+		builder.WriteByte(']')
+
+	case []map[string]interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+
+		return writePythonJSON(builder, items)
+
+	case map[string]interface{}:
+		var keys []string
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		builder.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+
+			err := writePythonJSON(builder, key)
+			if err != nil {
+				return err
+			}
+
+			builder.WriteString(": ")
+
+			err = writePythonJSON(builder, v[key])
+			if err != nil {
+				return err
+			}
+		}
+		builder.WriteByte('}')
+
+	default:
+		return fmt.Errorf("unsupported value %v of type %T", v, v)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package poetry_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-community/poetry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLockIsStale(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		projectDir string
+	)
+
+	writeLock := func(contentHash string) {
+		Expect(ioutil.WriteFile(filepath.Join(projectDir, "poetry.lock"), []byte(`[[package]]
+name = "requests"
+version = "2.25.1"
+
+[metadata]
+lock-version = "1.1"
+content-hash = "`+contentHash+`"
+`), 0644)).To(Succeed())
+	}
+
+	it.Before(func() {
+		var err error
+		projectDir, err = ioutil.TempDir("", "project")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ioutil.WriteFile(filepath.Join(projectDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+version = "0.1.0"
+
+[tool.poetry.dependencies]
+python = "^3.8"
+requests = "^2.25"
+
+[tool.poetry.group.test.dependencies]
+pytest = "^6.2"
+`), 0644)).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(projectDir)).To(Succeed())
+	})
+
+	context("when the lock was created by poetry 1.1", func() {
+		it.Before(func() {
+			writeLock("3148140bcf3280894d9f1e751674eeae5b5b2c21e9403742baab6bf29bddcab9")
+		})
+
+		it("is not stale", func() {
+			stale, err := poetry.LockIsStale(projectDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stale).To(BeFalse())
+		})
+	})
+
+	context("when the lock was created by a poetry that hashes dependency groups", func() {
+		it.Before(func() {
+			writeLock("2356a26f7d48100e29850828a9e6ac231a60d9d253a1660d12773d6e470c593b")
+		})
+
+		it("is not stale", func() {
+			stale, err := poetry.LockIsStale(projectDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stale).To(BeFalse())
+		})
+	})
+
+	context("when the project declares its dependencies in the [project] table", func() {
+		it.Before(func() {
+			Expect(ioutil.WriteFile(filepath.Join(projectDir, "pyproject.toml"), []byte(`[project]
+name = "some-app"
+version = "0.1.0"
+requires-python = ">=3.9"
+dependencies = ["requests>=2.25"]
+
+[tool.poetry.group.test.dependencies]
+pytest = "^6.2"
+`), 0644)).To(Succeed())
+
+			writeLock("8be7de2967322120891de9606a9618473c481ff901b11a1be9522c70af88f843")
+		})
+
+		it("is not stale", func() {
+			stale, err := poetry.LockIsStale(projectDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stale).To(BeFalse())
+		})
+	})
+
+	context("when the pyproject.toml has changed since the lock was created", func() {
+		it.Before(func() {
+			writeLock("3148140bcf3280894d9f1e751674eeae5b5b2c21e9403742baab6bf29bddcab9")
+
+			Expect(ioutil.WriteFile(filepath.Join(projectDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+version = "0.1.0"
+
+[tool.poetry.dependencies]
+python = "^3.8"
+requests = "^2.26"
+`), 0644)).To(Succeed())
+		})
+
+		it("is stale", func() {
+			stale, err := poetry.LockIsStale(projectDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stale).To(BeTrue())
+		})
+	})
+
+	context("when there is no poetry.lock", func() {
+		it("is not stale", func() {
+			stale, err := poetry.LockIsStale(projectDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stale).To(BeFalse())
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the poetry.lock cannot be parsed", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(projectDir, "poetry.lock"), []byte("%%%"), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := poetry.LockIsStale(projectDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse poetry.lock")))
+			})
+		})
+
+		context("when the pyproject.toml cannot be parsed", func() {
+			it.Before(func() {
+				writeLock("some-hash")
+				Expect(ioutil.WriteFile(filepath.Join(projectDir, "pyproject.toml"), []byte("%%%"), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := poetry.LockIsStale(projectDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse pyproject.toml")))
+			})
+		})
+	})
+}
@@ -0,0 +1,95 @@
+package poetry
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// ExportOptions configures which of the application's dependencies are
+// written to the requirements file by an ExportProcess.
+type ExportOptions struct {
+	// Env is the environment in which poetry is invoked. It must make the
+	// poetry executable and its packages available.
+	Env []string
+
+	// Dev indicates whether the development dependencies should be exported
+	// alongside the application's dependencies. It is ignored when groups are
+	// selected, in which case the dev group selects them.
+	Dev bool
+
+	// WithGroups, WithoutGroups and Extras select the dependency groups and
+	// extras to export, as they do for a VenvInstallProcess.
+	WithGroups    []string
+	WithoutGroups []string
+	Extras        []string
+
+	// WithoutHashes leaves the hashes of the packages out of the requirements
+	// file, so that pip does not run in hash-checking mode.
+	WithoutHashes bool
+}
+
+// PoetryExportProcess implements the ExportProcess interface.
+type PoetryExportProcess struct {
+	executable Executable
+	logger     scribe.Emitter
+}
+
+// NewPoetryExportProcess creates an instance of the PoetryExportProcess given an Executable that runs `poetry`.
+func NewPoetryExportProcess(executable Executable, logger scribe.Emitter) PoetryExportProcess {
+	return PoetryExportProcess{
+		executable: executable,
+		logger:     logger,
+	}
+}
+
+// Execute runs `poetry export` for the project located in workingDir, writing
+// the locked dependencies of the project in requirements.txt format to
+// outputPath. Poetry 1.2 and later only provide the export command through
+// poetry-plugin-export, which they bundle by default.
+func (p PoetryExportProcess) Execute(workingDir, outputPath string, options ExportOptions) error {
+	buffer := bytes.NewBuffer(nil)
+
+	// As with PoetryVenvInstallProcess, the deprecated --dev is only passed
+	// when no groups are selected.
+	groups := len(options.WithGroups) > 0 || len(options.WithoutGroups) > 0
+
+	args := []string{"export", "--format", "requirements.txt", "--output", outputPath}
+	if options.Dev && !groups {
+		args = append(args, "--dev")
+	}
+
+	if len(options.WithGroups) > 0 {
+		args = append(args, "--with", strings.Join(options.WithGroups, ","))
+	}
+
+	if len(options.WithoutGroups) > 0 {
+		args = append(args, "--without", strings.Join(options.WithoutGroups, ","))
+	}
+
+	for _, extra := range options.Extras {
+		args = append(args, "--extras", extra)
+	}
+
+	if options.WithoutHashes {
+		args = append(args, "--without-hashes")
+	}
+
+	p.logger.Subprocess("Running 'poetry %s'", strings.Join(args, " "))
+
+	err := p.executable.Execute(pexec.Execution{
+		Args:   args,
+		Dir:    workingDir,
+		Env:    options.Env,
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run poetry export:\n%s\nerror: %w", buffer.String(), err)
+	}
+
+	return nil
+}
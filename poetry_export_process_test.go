@@ -0,0 +1,104 @@
+package poetry_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-community/poetry"
+	"github.com/paketo-community/poetry/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPoetryExportProcess(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		outputPath string
+		executable *fakes.Executable
+		buffer     *bytes.Buffer
+
+		poetryExportProcess poetry.PoetryExportProcess
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		outputPath = filepath.Join(workingDir, "requirements.txt")
+
+		executable = &fakes.Executable{}
+
+		buffer = bytes.NewBuffer(nil)
+		poetryExportProcess = poetry.NewPoetryExportProcess(executable, scribe.NewEmitter(buffer))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("Execute", func() {
+		it("exports the dependencies to the requirements file", func() {
+			err := poetryExportProcess.Execute(workingDir, outputPath, poetry.ExportOptions{
+				Env: []string{"SOME_ENV=some-value"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"export", "--format", "requirements.txt", "--output", outputPath}))
+			Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+			Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal([]string{"SOME_ENV=some-value"}))
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Running 'poetry export --format requirements.txt --output %s'", outputPath)))
+		})
+
+		context("when dependencies are selected and hashes are not wanted", func() {
+			it("passes the selection to poetry export without the deprecated --dev", func() {
+				err := poetryExportProcess.Execute(workingDir, outputPath, poetry.ExportOptions{
+					Dev:           true,
+					WithGroups:    []string{"docs", "lint"},
+					WithoutGroups: []string{"test"},
+					Extras:        []string{"postgres"},
+					WithoutHashes: true,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{
+					"export", "--format", "requirements.txt", "--output", outputPath,
+					"--with", "docs,lint",
+					"--without", "test",
+					"--extras", "postgres",
+					"--without-hashes",
+				}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("poetry export fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						fmt.Fprintln(execution.Stdout, "stdout output")
+						fmt.Fprintln(execution.Stderr, "stderr output")
+						return errors.New("exporting dependencies failed")
+					}
+				})
+
+				it("returns an error", func() {
+					err := poetryExportProcess.Execute(workingDir, outputPath, poetry.ExportOptions{})
+					Expect(err).To(MatchError(ContainSubstring("failed to run poetry export:")))
+					Expect(err).To(MatchError(ContainSubstring("stdout output")))
+					Expect(err).To(MatchError(ContainSubstring("stderr output")))
+					Expect(err).To(MatchError(ContainSubstring("error: exporting dependencies failed")))
+				})
+			})
+		})
+	})
+}
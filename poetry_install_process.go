@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
 
 //go:generate faux --interface Executable --output fakes/executable.go
@@ -18,30 +21,92 @@ type Executable interface {
 // PoetryInstallProcess implements the InstallProcess interface.
 type PoetryInstallProcess struct {
 	executable Executable
+	logger     scribe.Emitter
 }
 
 // NewPoetryInstallProcess creates an instance of the PoetryInstallProcess given an Executable that runs `python`.
-func NewPoetryInstallProcess(executable Executable) PoetryInstallProcess {
+func NewPoetryInstallProcess(executable Executable, logger scribe.Emitter) PoetryInstallProcess {
 	return PoetryInstallProcess{
 		executable: executable,
+		logger:     logger,
 	}
 }
 
-// Execute installs the pip binary from source code located in the given srcPath into the a layer path designated by targetLayerPath.
-func (p PoetryInstallProcess) Execute(srcPath, targetLayerPath string) error {
+// Execute installs poetry from source code located in the given srcPath into
+// a virtual environment within the layer designated by targetLayerPath. When
+// srcPath contains wheels, poetry is installed from them alone. Only
+// the poetry executable is linked into the bin directory of the layer, so
+// that poetry's own dependencies never appear on the application's path. Pip
+// caches the packages it downloads or builds in cachePath.
+func (p PoetryInstallProcess) Execute(srcPath, targetLayerPath, cachePath string) error {
 	buffer := bytes.NewBuffer(nil)
+	venvPath := filepath.Join(targetLayerPath, "venv")
+
+	args := []string{"-m", "venv", venvPath}
+	p.logger.Action("Running 'python %s'", strings.Join(args, " "))
 
 	err := p.executable.Execute(pexec.Execution{
-		// Install pip from source with the pip that comes pre-installed with cpython
-		Args: []string{"install", "poetry", "--user", fmt.Sprintf("--find-links=%s", srcPath)},
-		// Set the PYTHONUSERBASE to ensure that pip is installed to the newly created target layer.
-		Env:    append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath)),
+		Args:   args,
+		Env:    os.Environ(),
 		Stdout: buffer,
 		Stderr: buffer,
 	})
+	if err != nil {
+		return fmt.Errorf("failed to create poetry virtual environment:\n%s\nerror: %w", buffer.String(), err)
+	}
 
+	// A dependency that bundles wheels is expected to contain poetry and all of
+	// its dependencies, so it is installed without consulting any package
+	// index. This keeps the install working on builders without internet
+	// access, and makes a missing wheel fail the build rather than hang it.
+	wheels, err := filepath.Glob(filepath.Join(srcPath, "*.whl"))
 	if err != nil {
+		return fmt.Errorf("failed to find bundled poetry wheels: %w", err)
+	}
+	offline := len(wheels) > 0
+
+	// Install poetry from source with the pip of the virtual environment
+	args = []string{"-m", "pip", "install", "poetry", fmt.Sprintf("--find-links=%s", srcPath)}
+	if offline {
+		args = append(args, "--no-index")
+	}
+	p.logger.Action("Running 'python %s'", strings.Join(args, " "))
+
+	// Put the virtual environment first on the PATH to ensure that its python,
+	// and therefore its pip, is the one that is invoked.
+	env := append(os.Environ(),
+		fmt.Sprintf("PATH=%s", prependPath(filepath.Join(venvPath, "bin"), os.Getenv("PATH"))),
+		fmt.Sprintf("VIRTUAL_ENV=%s", venvPath),
+		fmt.Sprintf("PIP_CACHE_DIR=%s", cachePath),
+	)
+	if offline {
+		env = append(env, "PIP_DISABLE_PIP_VERSION_CHECK=1")
+	}
+
+	buffer.Reset()
+	err = p.executable.Execute(pexec.Execution{
+		Args:   args,
+		Env:    env,
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		if offline {
+			return fmt.Errorf("failed to configure poetry from bundled wheels, the poetry dependency may be missing a required wheel:\n%s\nerror: %w", buffer.String(), err)
+		}
+
 		return fmt.Errorf("failed to configure poetry:\n%s\nerror: %w", buffer.String(), err)
 	}
+
+	err = os.MkdirAll(filepath.Join(targetLayerPath, "bin"), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create poetry bin directory: %w", err)
+	}
+
+	err = os.Symlink(filepath.Join("..", "venv", "bin", "poetry"), filepath.Join(targetLayerPath, "bin", "poetry"))
+	if err != nil {
+		return fmt.Errorf("failed to link poetry executable: %w", err)
+	}
+
 	return nil
 }
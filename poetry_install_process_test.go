@@ -1,13 +1,16 @@
 package poetry_test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
-	"github.com/paketo-buildpacks/packit/pexec"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 	"github.com/paketo-community/poetry"
 	"github.com/paketo-community/poetry/fakes"
 	"github.com/sclevine/spec"
@@ -22,6 +25,8 @@ func testPoetryInstallProcess(t *testing.T, context spec.G, it spec.S) {
 		srcLayerPath    string
 		targetLayerPath string
 		executable      *fakes.Executable
+		executions      []pexec.Execution
+		buffer          *bytes.Buffer
 
 		poetryInstallProcess poetry.PoetryInstallProcess
 	)
@@ -34,26 +39,107 @@ func testPoetryInstallProcess(t *testing.T, context spec.G, it spec.S) {
 		targetLayerPath, err = ioutil.TempDir("", "poetry")
 		Expect(err).NotTo(HaveOccurred())
 
+		executions = []pexec.Execution{}
 		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			executions = append(executions, execution)
+			return nil
+		}
 
-		poetryInstallProcess = poetry.NewPoetryInstallProcess(executable)
+		buffer = bytes.NewBuffer(nil)
+		poetryInstallProcess = poetry.NewPoetryInstallProcess(executable, scribe.NewEmitter(buffer))
 	})
 
 	context("Execute", func() {
 		context("there is a poetry dependency to install", func() {
-			it("installs it to the poetry layer", func() {
-				err := poetryInstallProcess.Execute(srcLayerPath, targetLayerPath)
+			it("installs it into a virtual environment in the poetry layer", func() {
+				err := poetryInstallProcess.Execute(srcLayerPath, targetLayerPath, "some-cache-path")
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", targetLayerPath))))
-				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"install", "poetry", "--user", fmt.Sprintf("--find-links=%s", srcLayerPath)}))
+				venvPath := filepath.Join(targetLayerPath, "venv")
+
+				Expect(executions).To(HaveLen(2))
+				Expect(executions[0].Args).To(Equal([]string{"-m", "venv", venvPath}))
+				Expect(executions[0].Env).To(Equal(os.Environ()))
+
+				Expect(executions[1].Args).To(Equal([]string{"-m", "pip", "install", "poetry", fmt.Sprintf("--find-links=%s", srcLayerPath)}))
+				Expect(executions[1].Env).To(ContainElement(MatchRegexp(`^PATH=%s`, filepath.Join(venvPath, "bin"))))
+				Expect(executions[1].Env).To(ContainElement(fmt.Sprintf("VIRTUAL_ENV=%s", venvPath)))
+				Expect(executions[1].Env).To(ContainElement("PIP_CACHE_DIR=some-cache-path"))
+				Expect(executions[1].Env).NotTo(ContainElement("PIP_DISABLE_PIP_VERSION_CHECK=1"))
+
+				link, err := os.Readlink(filepath.Join(targetLayerPath, "bin", "poetry"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(link).To(Equal(filepath.Join("..", "venv", "bin", "poetry")))
+
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Running 'python -m venv %s'", venvPath)))
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Running 'python -m pip install poetry --find-links=%s'", srcLayerPath)))
+			})
+		})
+
+		context("the poetry dependency bundles wheels", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(srcLayerPath, "poetry-1.1.6-py2.py3-none-any.whl"), nil, 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(srcLayerPath, "cleo-0.8.1-py2.py3-none-any.whl"), nil, 0644)).To(Succeed())
+			})
+
+			it("installs it without consulting a package index", func() {
+				err := poetryInstallProcess.Execute(srcLayerPath, targetLayerPath, "some-cache-path")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions).To(HaveLen(2))
+				Expect(executions[1].Args).To(Equal([]string{"-m", "pip", "install", "poetry", fmt.Sprintf("--find-links=%s", srcLayerPath), "--no-index"}))
+				Expect(executions[1].Env).To(ContainElement("PIP_DISABLE_PIP_VERSION_CHECK=1"))
+
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("Running 'python -m pip install poetry --find-links=%s --no-index'", srcLayerPath)))
+			})
+
+			context("when a wheel is missing", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						if execution.Args[1] == "venv" {
+							return nil
+						}
+
+						fmt.Fprintln(execution.Stderr, "ERROR: No matching distribution found for crashtest")
+						return errors.New("exit status 1")
+					}
+				})
+
+				it("returns an error", func() {
+					err := poetryInstallProcess.Execute(srcLayerPath, targetLayerPath, "some-cache-path")
+					Expect(err).To(MatchError(ContainSubstring("failed to configure poetry from bundled wheels, the poetry dependency may be missing a required wheel")))
+					Expect(err).To(MatchError(ContainSubstring("No matching distribution found for crashtest")))
+				})
 			})
 		})
 
 		context("failure cases", func() {
+			context("the virtual environment cannot be created", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						fmt.Fprintln(execution.Stdout, "stdout output")
+						fmt.Fprintln(execution.Stderr, "stderr output")
+						return errors.New("creating venv failed")
+					}
+				})
+
+				it("returns an error", func() {
+					err := poetryInstallProcess.Execute(srcLayerPath, targetLayerPath, "some-cache-path")
+					Expect(err).To(MatchError(ContainSubstring("failed to create poetry virtual environment")))
+					Expect(err).To(MatchError(ContainSubstring("creating venv failed")))
+					Expect(err).To(MatchError(ContainSubstring("stdout output")))
+					Expect(err).To(MatchError(ContainSubstring("stderr output")))
+				})
+			})
+
 			context("the poetry install process fails", func() {
 				it.Before(func() {
 					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						if execution.Args[1] == "venv" {
+							return nil
+						}
+
 						fmt.Fprintln(execution.Stdout, "stdout output")
 						fmt.Fprintln(execution.Stderr, "stderr output")
 						return errors.New("installing poetry failed")
@@ -61,7 +147,8 @@ func testPoetryInstallProcess(t *testing.T, context spec.G, it spec.S) {
 				})
 
 				it("returns an error", func() {
-					err := poetryInstallProcess.Execute(srcLayerPath, targetLayerPath)
+					err := poetryInstallProcess.Execute(srcLayerPath, targetLayerPath, "some-cache-path")
+					Expect(err).To(MatchError(ContainSubstring("failed to configure poetry")))
 					Expect(err).To(MatchError(ContainSubstring("installing poetry failed")))
 					Expect(err).To(MatchError(ContainSubstring("stdout output")))
 					Expect(err).To(MatchError(ContainSubstring("stderr output")))
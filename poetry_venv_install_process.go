@@ -0,0 +1,128 @@
+package poetry
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// VenvInstallOptions configures how the application's dependencies are
+// installed by a VenvInstallProcess.
+type VenvInstallOptions struct {
+	// Env is the environment in which poetry is invoked. It must make the
+	// poetry executable and its packages available.
+	Env []string
+
+	// Dev indicates whether the development dependencies should be installed
+	// alongside the application's dependencies. It is ignored when groups are
+	// selected, in which case the dev group selects them.
+	Dev bool
+
+	// WithGroups are the optional dependency groups to install in addition to
+	// the default ones, and WithoutGroups the groups to leave out. Selecting
+	// groups requires poetry 1.2 or later.
+	WithGroups    []string
+	WithoutGroups []string
+
+	// Extras are the extras of the project to install.
+	Extras []string
+
+	// CacheDir is the directory in which poetry caches the packages it
+	// downloads. Poetry's default cache is used when it is empty.
+	CacheDir string
+}
+
+// PoetryVenvInstallProcess implements the VenvInstallProcess interface.
+type PoetryVenvInstallProcess struct {
+	executable Executable
+	logger     scribe.Emitter
+}
+
+// NewPoetryVenvInstallProcess creates an instance of the PoetryVenvInstallProcess given an Executable that runs `poetry`.
+func NewPoetryVenvInstallProcess(executable Executable, logger scribe.Emitter) PoetryVenvInstallProcess {
+	return PoetryVenvInstallProcess{
+		executable: executable,
+		logger:     logger,
+	}
+}
+
+// Execute runs `poetry install` for the project located in workingDir,
+// creating its virtual environment within the layer designated by
+// targetLayerPath. It returns the path to the virtual environment.
+func (p PoetryVenvInstallProcess) Execute(workingDir, targetLayerPath string, options VenvInstallOptions) (string, error) {
+	buffer := bytes.NewBuffer(nil)
+
+	// Poetry 1.2 deprecates --no-dev, and poetry 2 removes it, so it is only
+	// passed when no groups are selected. The development dependencies are
+	// otherwise selected as the dev group.
+	groups := len(options.WithGroups) > 0 || len(options.WithoutGroups) > 0
+
+	args := []string{"install"}
+	if !options.Dev && !groups {
+		args = append(args, "--no-dev")
+	}
+
+	if len(options.WithGroups) > 0 {
+		args = append(args, "--with", strings.Join(options.WithGroups, ","))
+	}
+
+	if len(options.WithoutGroups) > 0 {
+		args = append(args, "--without", strings.Join(options.WithoutGroups, ","))
+	}
+
+	for _, extra := range options.Extras {
+		args = append(args, "--extras", extra)
+	}
+
+	// Set POETRY_VIRTUALENVS_PATH to ensure that the virtual environment is
+	// created inside the target layer. Poetry gives its environment variables
+	// precedence over a poetry.toml in the project, so the remaining settings
+	// keep such a file from installing into the project directory or into the
+	// python installation itself.
+	env := append(options.Env,
+		"POETRY_VIRTUALENVS_CREATE=true",
+		"POETRY_VIRTUALENVS_IN_PROJECT=false",
+		fmt.Sprintf("POETRY_VIRTUALENVS_PATH=%s", targetLayerPath),
+	)
+	if options.CacheDir != "" {
+		env = append(env, fmt.Sprintf("POETRY_CACHE_DIR=%s", options.CacheDir))
+	}
+
+	p.logger.Subprocess("Running 'poetry %s'", strings.Join(args, " "))
+
+	err := p.executable.Execute(pexec.Execution{
+		Args:   args,
+		Dir:    workingDir,
+		Env:    env,
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to run poetry install:\n%s\nerror: %w", buffer.String(), err)
+	}
+
+	buffer.Reset()
+	venvPath := bytes.NewBuffer(nil)
+
+	err = p.executable.Execute(pexec.Execution{
+		// Ask poetry where it created the virtual environment for the project.
+		Args:   []string{"env", "info", "--path"},
+		Dir:    workingDir,
+		Env:    env,
+		Stdout: venvPath,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to locate virtual environment:\n%s\nerror: %w", buffer.String(), err)
+	}
+
+	path := strings.TrimSpace(venvPath.String())
+	if !withinDir(targetLayerPath, path) {
+		return "", fmt.Errorf("failed to install into virtual environment: poetry installed into %q, which is not within %s", path, targetLayerPath)
+	}
+
+	return path, nil
+}
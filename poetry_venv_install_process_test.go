@@ -0,0 +1,171 @@
+package poetry_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-community/poetry"
+	"github.com/paketo-community/poetry/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPoetryVenvInstallProcess(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir      string
+		targetLayerPath string
+		executions      []pexec.Execution
+		executable      *fakes.Executable
+		buffer          *bytes.Buffer
+
+		poetryVenvInstallProcess poetry.PoetryVenvInstallProcess
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		targetLayerPath, err = ioutil.TempDir("", "poetry-venv")
+		Expect(err).NotTo(HaveOccurred())
+
+		executions = []pexec.Execution{}
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			executions = append(executions, execution)
+			if execution.Args[0] == "env" {
+				fmt.Fprintln(execution.Stdout, filepath.Join(targetLayerPath, "some-app-py3.8"))
+			}
+			return nil
+		}
+
+		buffer = bytes.NewBuffer(nil)
+		poetryVenvInstallProcess = poetry.NewPoetryVenvInstallProcess(executable, scribe.NewEmitter(buffer))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+		Expect(os.RemoveAll(targetLayerPath)).To(Succeed())
+	})
+
+	context("Execute", func() {
+		it("installs the dependencies into a venv in the target layer", func() {
+			venvPath, err := poetryVenvInstallProcess.Execute(workingDir, targetLayerPath, poetry.VenvInstallOptions{
+				Env: []string{"SOME_ENV=some-value"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(venvPath).To(Equal(filepath.Join(targetLayerPath, "some-app-py3.8")))
+
+			Expect(executions).To(HaveLen(2))
+			Expect(executions[0].Args).To(Equal([]string{"install", "--no-dev"}))
+			Expect(executions[0].Dir).To(Equal(workingDir))
+			Expect(executions[0].Env).To(Equal([]string{
+				"SOME_ENV=some-value",
+				"POETRY_VIRTUALENVS_CREATE=true",
+				"POETRY_VIRTUALENVS_IN_PROJECT=false",
+				fmt.Sprintf("POETRY_VIRTUALENVS_PATH=%s", targetLayerPath),
+			}))
+
+			Expect(executions[1].Args).To(Equal([]string{"env", "info", "--path"}))
+			Expect(buffer.String()).To(ContainSubstring("Running 'poetry install --no-dev'"))
+			Expect(executions[1].Dir).To(Equal(workingDir))
+			Expect(executions[1].Env).To(Equal(executions[0].Env))
+		})
+
+		context("when a cache directory is given", func() {
+			it("points poetry at it", func() {
+				_, err := poetryVenvInstallProcess.Execute(workingDir, targetLayerPath, poetry.VenvInstallOptions{CacheDir: "some-cache-dir"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions[0].Env).To(ContainElement("POETRY_CACHE_DIR=some-cache-dir"))
+			})
+		})
+
+		context("when development dependencies are requested", func() {
+			it("installs them", func() {
+				_, err := poetryVenvInstallProcess.Execute(workingDir, targetLayerPath, poetry.VenvInstallOptions{Dev: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions[0].Args).To(Equal([]string{"install"}))
+			})
+		})
+
+		context("when groups and extras are selected", func() {
+			it("passes them to poetry install without the deprecated --no-dev", func() {
+				_, err := poetryVenvInstallProcess.Execute(workingDir, targetLayerPath, poetry.VenvInstallOptions{
+					WithGroups:    []string{"docs", "lint"},
+					WithoutGroups: []string{"test"},
+					Extras:        []string{"postgres", "mysql"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions[0].Args).To(Equal([]string{"install", "--with", "docs,lint", "--without", "test", "--extras", "postgres", "--extras", "mysql"}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("poetry install fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						fmt.Fprintln(execution.Stdout, "stdout output")
+						fmt.Fprintln(execution.Stderr, "stderr output")
+						return errors.New("installing dependencies failed")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := poetryVenvInstallProcess.Execute(workingDir, targetLayerPath, poetry.VenvInstallOptions{})
+					Expect(err).To(MatchError(ContainSubstring("failed to run poetry install:")))
+					Expect(err).To(MatchError(ContainSubstring("stdout output")))
+					Expect(err).To(MatchError(ContainSubstring("stderr output")))
+					Expect(err).To(MatchError(ContainSubstring("error: installing dependencies failed")))
+				})
+			})
+
+			context("poetry reports a venv outside of the target layer", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						if execution.Args[0] == "env" {
+							fmt.Fprintln(execution.Stdout, filepath.Join(workingDir, ".venv"))
+						}
+						return nil
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := poetryVenvInstallProcess.Execute(workingDir, targetLayerPath, poetry.VenvInstallOptions{})
+					Expect(err).To(MatchError(fmt.Sprintf("failed to install into virtual environment: poetry installed into %q, which is not within %s", filepath.Join(workingDir, ".venv"), targetLayerPath)))
+				})
+			})
+
+			context("locating the venv fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						if execution.Args[0] == "env" {
+							fmt.Fprintln(execution.Stderr, "stderr output")
+							return errors.New("env info failed")
+						}
+						return nil
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := poetryVenvInstallProcess.Execute(workingDir, targetLayerPath, poetry.VenvInstallOptions{})
+					Expect(err).To(MatchError(ContainSubstring("failed to locate virtual environment:")))
+					Expect(err).To(MatchError(ContainSubstring("stderr output")))
+					Expect(err).To(MatchError(ContainSubstring("error: env info failed")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,56 @@
+package poetry
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// PoetryVerifyProcess implements the VerifyProcess interface.
+type PoetryVerifyProcess struct {
+	executable Executable
+	logger     scribe.Emitter
+}
+
+// NewPoetryVerifyProcess creates an instance of the PoetryVerifyProcess given an Executable that runs `poetry`.
+func NewPoetryVerifyProcess(executable Executable, logger scribe.Emitter) PoetryVerifyProcess {
+	return PoetryVerifyProcess{
+		executable: executable,
+		logger:     logger,
+	}
+}
+
+// Execute runs `poetry --version` using the poetry executable in the bin
+// directory of the layer designated by layerPath, and returns an error
+// containing the output of the command if poetry cannot be run or reports a
+// version other than the given version.
+func (p PoetryVerifyProcess) Execute(layerPath, version string) error {
+	buffer := bytes.NewBuffer(nil)
+	args := []string{"--version"}
+
+	p.logger.Action("Running 'poetry %s'", strings.Join(args, " "))
+
+	err := p.executable.Execute(pexec.Execution{
+		Args:   args,
+		Env:    append(os.Environ(), fmt.Sprintf("PATH=%s", prependPath(filepath.Join(layerPath, "bin"), os.Getenv("PATH")))),
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run poetry:\n%s\nerror: %w", buffer.String(), err)
+	}
+
+	// The output is of the form "Poetry version 1.1.4", or "Poetry (version
+	// 1.2.0)" in later releases.
+	fields := strings.Fields(buffer.String())
+	if len(fields) == 0 || strings.Trim(fields[len(fields)-1], "()") != version {
+		return fmt.Errorf("installed poetry does not report version %s:\n%s", version, buffer.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package poetry_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-community/poetry"
+	"github.com/paketo-community/poetry/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPoetryVerifyProcess(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		executable *fakes.Executable
+		buffer     *bytes.Buffer
+
+		poetryVerifyProcess poetry.PoetryVerifyProcess
+	)
+
+	it.Before(func() {
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			fmt.Fprintln(execution.Stdout, "Poetry version 1.1.4")
+			return nil
+		}
+
+		buffer = bytes.NewBuffer(nil)
+		poetryVerifyProcess = poetry.NewPoetryVerifyProcess(executable, scribe.NewEmitter(buffer))
+	})
+
+	context("Execute", func() {
+		it("runs poetry from the layer", func() {
+			err := poetryVerifyProcess.Execute("some-layer-path", "1.1.4")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"--version"}))
+			Expect(executable.ExecuteCall.Receives.Execution.Env).To(ContainElement(MatchRegexp(`^PATH=%s`, filepath.Join("some-layer-path", "bin"))))
+
+			Expect(buffer.String()).To(ContainSubstring("Running 'poetry --version'"))
+		})
+
+		context("when poetry reports its version in parentheses", func() {
+			it.Before(func() {
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					fmt.Fprintln(execution.Stdout, "Poetry (version 1.2.0)")
+					return nil
+				}
+			})
+
+			it("accepts the version", func() {
+				err := poetryVerifyProcess.Execute("some-layer-path", "1.2.0")
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		context("when the PATH is empty", func() {
+			var path string
+
+			it.Before(func() {
+				path = os.Getenv("PATH")
+				Expect(os.Setenv("PATH", "")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Setenv("PATH", path)).To(Succeed())
+			})
+
+			it("only puts the layer on the PATH", func() {
+				err := poetryVerifyProcess.Execute("some-layer-path", "1.1.4")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(ContainElement(fmt.Sprintf("PATH=%s", filepath.Join("some-layer-path", "bin"))))
+			})
+		})
+
+		context("failure cases", func() {
+			context("poetry cannot be run", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						fmt.Fprintln(execution.Stderr, "stderr output")
+						return errors.New("running poetry failed")
+					}
+				})
+
+				it("returns an error", func() {
+					err := poetryVerifyProcess.Execute("some-layer-path", "1.1.4")
+					Expect(err).To(MatchError(ContainSubstring("failed to run poetry:")))
+					Expect(err).To(MatchError(ContainSubstring("stderr output")))
+					Expect(err).To(MatchError(ContainSubstring("error: running poetry failed")))
+				})
+			})
+
+			context("poetry reports a different version", func() {
+				it("returns an error containing the output", func() {
+					err := poetryVerifyProcess.Execute("some-layer-path", "2.0.0")
+					Expect(err).To(MatchError(ContainSubstring("installed poetry does not report version 2.0.0")))
+					Expect(err).To(MatchError(ContainSubstring("Poetry version 1.1.4")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,61 @@
+package poetry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectPath returns the directory of the poetry project within the given
+// working directory. It is the working directory itself unless
+// BP_POETRY_PROJECT_PATH names a subdirectory, in which case that subdirectory
+// must stay within the working directory and contain a pyproject.toml.
+func ProjectPath(workingDir string) (string, error) {
+	projectPath := os.Getenv("BP_POETRY_PROJECT_PATH")
+	if projectPath == "" {
+		return workingDir, nil
+	}
+
+	if filepath.IsAbs(projectPath) {
+		return "", fmt.Errorf("invalid BP_POETRY_PROJECT_PATH %q: must be relative to the application directory", projectPath)
+	}
+
+	path := filepath.Join(workingDir, projectPath)
+	if !withinDir(workingDir, path) {
+		return "", fmt.Errorf("invalid BP_POETRY_PROJECT_PATH %q: must not escape the application directory", projectPath)
+	}
+
+	_, err := os.Stat(filepath.Join(path, PyProject))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("invalid BP_POETRY_PROJECT_PATH %q: %s does not exist", projectPath, filepath.Join(projectPath, PyProject))
+		}
+
+		return "", fmt.Errorf("invalid BP_POETRY_PROJECT_PATH %q: %w", projectPath, err)
+	}
+
+	// Compare the paths with their symlinks resolved as well, so that a
+	// symlinked subdirectory cannot lead outside of the working directory.
+	resolvedWorkingDir, err := filepath.EvalSymlinks(workingDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve application directory: %w", err)
+	}
+
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid BP_POETRY_PROJECT_PATH %q: %w", projectPath, err)
+	}
+
+	if !withinDir(resolvedWorkingDir, resolvedPath) {
+		return "", fmt.Errorf("invalid BP_POETRY_PROJECT_PATH %q: must not escape the application directory", projectPath)
+	}
+
+	return path, nil
+}
+
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,127 @@
+package poetry_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-community/poetry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testProjectPath(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(workingDir, "services", "some-service"), os.ModePerm)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "services", "some-service", "pyproject.toml"), nil, 0644)).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.Unsetenv("BP_POETRY_PROJECT_PATH")).To(Succeed())
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("when BP_POETRY_PROJECT_PATH is not set", func() {
+		it("returns the working directory", func() {
+			path, err := poetry.ProjectPath(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(workingDir))
+		})
+	})
+
+	context("when BP_POETRY_PROJECT_PATH is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "services/some-service")).To(Succeed())
+		})
+
+		it("returns the project subdirectory", func() {
+			path, err := poetry.ProjectPath(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(workingDir, "services", "some-service")))
+		})
+	})
+
+	context("when BP_POETRY_PROJECT_PATH is a symlink within the working directory", func() {
+		it.Before(func() {
+			Expect(os.Symlink(filepath.Join(workingDir, "services", "some-service"), filepath.Join(workingDir, "linked-service"))).To(Succeed())
+			Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "linked-service")).To(Succeed())
+		})
+
+		it("returns the project subdirectory", func() {
+			path, err := poetry.ProjectPath(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(workingDir, "linked-service")))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when BP_POETRY_PROJECT_PATH is absolute", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "/services/some-service")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := poetry.ProjectPath(workingDir)
+				Expect(err).To(MatchError(`invalid BP_POETRY_PROJECT_PATH "/services/some-service": must be relative to the application directory`))
+			})
+		})
+
+		context("when BP_POETRY_PROJECT_PATH escapes the working directory", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "services/../../some-service")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := poetry.ProjectPath(workingDir)
+				Expect(err).To(MatchError(`invalid BP_POETRY_PROJECT_PATH "services/../../some-service": must not escape the application directory`))
+			})
+		})
+
+		context("when BP_POETRY_PROJECT_PATH is a symlink that leads outside the working directory", func() {
+			var outsideDir string
+
+			it.Before(func() {
+				var err error
+				outsideDir, err = ioutil.TempDir("", "outside")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(ioutil.WriteFile(filepath.Join(outsideDir, "pyproject.toml"), nil, 0644)).To(Succeed())
+				Expect(os.Symlink(outsideDir, filepath.Join(workingDir, "services", "linked-service"))).To(Succeed())
+
+				Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "services/linked-service")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.RemoveAll(outsideDir)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := poetry.ProjectPath(workingDir)
+				Expect(err).To(MatchError(`invalid BP_POETRY_PROJECT_PATH "services/linked-service": must not escape the application directory`))
+			})
+		})
+
+		context("when BP_POETRY_PROJECT_PATH does not contain a pyproject.toml", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_POETRY_PROJECT_PATH", "services")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := poetry.ProjectPath(workingDir)
+				Expect(err).To(MatchError(`invalid BP_POETRY_PROJECT_PATH "services": services/pyproject.toml does not exist`))
+			})
+		})
+	})
+}
@@ -1,26 +1,42 @@
 package poetry
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
 
+// PyProjParser implements the ProjectParser interface.
 type PyProjParser struct{}
 
+// NewPyProjParser creates an instance of the PyProjParser.
 func NewPyProjParser() PyProjParser {
 	return PyProjParser{}
 }
 
-func (p PyProjParser) Parse(path string) (bool, string, error) {
+// pep508Requirement matches the name and version specifier of a requirement
+// such as "poetry>=1.1,<1.2".
+var pep508Requirement = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*\(?([^;)]*)\)?`)
 
-	pyProject, err := os.Open(filepath.Join(path, PyProject))
-	if err != nil {
-		panic(err)
-	}
+// Parse reads the pyproject.toml located in the given path and returns the
+// poetry configuration found in it.
+func (p PyProjParser) Parse(path string) (ProjectMetadata, error) {
+
+	pyProjectPath := filepath.Join(path, PyProject)
 
 	var pyProjectTOML struct {
+		BuildSystem struct {
+			Requires     []string `toml:"requires"`
+			BuildBackend string   `toml:"build-backend"`
+		} `toml:"build-system"`
+		Project struct {
+			RequiresPython string `toml:"requires-python"`
+		} `toml:"project"`
 		Tool struct {
 			Poetry struct {
 				Name         string `toml:"name"`
@@ -31,17 +47,104 @@ func (p PyProjParser) Parse(path string) (bool, string, error) {
 		} `toml:"tool"`
 	}
 
-	_, err = toml.DecodeReader(pyProject, &pyProjectTOML)
+	md, err := toml.DecodeFile(pyProjectPath, &pyProjectTOML)
 	if err != nil {
-		panic(err)
+		if errors.Is(err, os.ErrNotExist) {
+			return ProjectMetadata{}, nil
+		}
+
+		return ProjectMetadata{}, fmt.Errorf("failed to parse %s: %w", pyProjectPath, err)
+	}
+
+	var metadata ProjectMetadata
+
+	// The standard [project] table is preferred over the poetry specific
+	// dependency table, as poetry 2 does.
+	pythonVersion, pythonVersionSource := pyProjectTOML.Project.RequiresPython, PyProject+":project.requires-python"
+	if pythonVersion == "" {
+		pythonVersion, pythonVersionSource = pyProjectTOML.Tool.Poetry.Dependencies.Python, PyProject+":tool.poetry.dependencies.python"
+	}
+
+	if pythonVersion != "" {
+		metadata.PythonVersion, err = TranslateConstraint(pythonVersion)
+		if err != nil {
+			return ProjectMetadata{}, fmt.Errorf("failed to parse python version in %s: %w", pyProjectPath, err)
+		}
+		metadata.PythonVersionSource = pythonVersionSource
+	}
+
+	_, err = os.Stat(filepath.Join(path, PoetryLock))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return ProjectMetadata{}, fmt.Errorf("failed to stat %s: %w", PoetryLock, err)
 	}
+	hasLock := err == nil
+
+	switch {
+	case md.IsDefined("tool", "poetry"):
+		metadata.Detected = true
+		metadata.DetectionReason = "pyproject.toml contains a [tool.poetry] table"
+	case hasLock:
+		metadata.Detected = true
+		metadata.DetectionReason = "poetry.lock is present"
+	case strings.HasPrefix(pyProjectTOML.BuildSystem.BuildBackend, "poetry.core.") || strings.HasPrefix(pyProjectTOML.BuildSystem.BuildBackend, "poetry.masonry."):
+		metadata.Detected = true
+		metadata.DetectionReason = fmt.Sprintf("pyproject.toml uses the %s build backend", pyProjectTOML.BuildSystem.BuildBackend)
+	}
+
+	// The scripts are collected from the decoded keys so that their order
+	// in the file is preserved.
+	for _, key := range md.Keys() {
+		if len(key) == 4 && key[0] == "tool" && key[1] == "poetry" && key[2] == "scripts" {
+			metadata.Scripts = append(metadata.Scripts, key[3])
+		}
+	}
+
+	// The main group is implicit, and the legacy dev-dependencies table is
+	// treated by poetry as the dev group.
+	metadata.Groups = []string{"main"}
+	if md.IsDefined("tool", "poetry", "dev-dependencies") {
+		metadata.Groups = append(metadata.Groups, "dev")
+	}
+
+	for _, key := range md.Keys() {
+		switch {
+		// A group may only be declared through its dependencies table, as in
+		// [tool.poetry.group.<name>.dependencies].
+		case len(key) >= 4 && key[0] == "tool" && key[1] == "poetry" && key[2] == "group":
+			if !containsString(metadata.Groups, key[3]) {
+				metadata.Groups = append(metadata.Groups, key[3])
+			}
+
+		case len(key) == 4 && key[0] == "tool" && key[1] == "poetry" && key[2] == "extras",
+			len(key) == 3 && key[0] == "project" && key[1] == "optional-dependencies":
+			if !containsString(metadata.Extras, key[len(key)-1]) {
+				metadata.Extras = append(metadata.Extras, key[len(key)-1])
+			}
+		}
+	}
+
+	// Only a requirement on poetry itself constrains the poetry version.
+	// poetry-core is versioned independently of poetry, so a poetry-core
+	// requirement says nothing about which poetry release is needed.
+	for _, requirement := range pyProjectTOML.BuildSystem.Requires {
+		matches := pep508Requirement.FindStringSubmatch(requirement)
+		if matches == nil || !strings.EqualFold(matches[1], Poetry) {
+			continue
+		}
 
-	var detected bool
-	pyVersion := pyProjectTOML.Tool.Poetry.Dependencies.Python
+		specifier := strings.Join(strings.Fields(matches[2]), "")
+		if strings.HasPrefix(specifier, "@") {
+			// A direct reference such as "poetry @ https://..." has no version.
+			continue
+		}
 
-	if pyProjectTOML.Tool.Poetry.Name != "" {
-		detected = true
+		// PEP 508 specifiers such as "==1.1.6" or "~=1.1" are not understood by
+		// the semver constraints that dependencies are resolved with.
+		metadata.PoetryVersion, err = TranslateConstraint(specifier)
+		if err != nil {
+			return ProjectMetadata{}, fmt.Errorf("failed to parse poetry version in %s: %w", pyProjectPath, err)
+		}
 	}
 
-	return detected, pyVersion, nil
+	return metadata, nil
 }
@@ -1,6 +1,7 @@
 package poetry_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"testing"
@@ -40,12 +41,286 @@ python = "*"`), 0644)).To(Succeed())
 			})
 
 			it("parses configuration", func() {
-				detected, pyVersion, err := pyProjParser.Parse(workingDir)
+				project, err := pyProjParser.Parse(workingDir)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(detected).To(Equal(true))
-				Expect(pyVersion).To(Equal("*"))
+				Expect(project.Detected).To(Equal(true))
+				Expect(project.DetectionReason).To(Equal("pyproject.toml contains a [tool.poetry] table"))
+				Expect(project.PythonVersion).To(Equal("*"))
+				Expect(project.PythonVersionSource).To(Equal("pyproject.toml:tool.poetry.dependencies.python"))
+				Expect(project.PoetryVersion).To(BeEmpty())
 			})
 		})
 
+		context("when pyproject.toml only contains [tool.poetry.dependencies]", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[tool.poetry.dependencies]
+python = "^3.8"`), 0644)).To(Succeed())
+			})
+
+			it("detects", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.Detected).To(BeTrue())
+				Expect(project.DetectionReason).To(Equal("pyproject.toml contains a [tool.poetry] table"))
+			})
+		})
+
+		context("when there is a poetry.lock", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[project]
+name = "some-app"`), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "poetry.lock"), nil, 0644)).To(Succeed())
+			})
+
+			it("detects", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.Detected).To(BeTrue())
+				Expect(project.DetectionReason).To(Equal("poetry.lock is present"))
+			})
+		})
+
+		context("when pyproject.toml uses the poetry-core build backend", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[project]
+name = "some-app"
+
+[build-system]
+requires = ["poetry-core>=1.0.0"]
+build-backend = "poetry.core.masonry.api"`), 0644)).To(Succeed())
+			})
+
+			it("detects", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.Detected).To(BeTrue())
+				Expect(project.DetectionReason).To(Equal("pyproject.toml uses the poetry.core.masonry.api build backend"))
+			})
+		})
+
+		context("when pyproject.toml is not managed by poetry", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[project]
+name = "some-app"
+
+[build-system]
+requires = ["setuptools"]
+build-backend = "setuptools.build_meta"`), 0644)).To(Succeed())
+			})
+
+			it("does not detect", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.Detected).To(BeFalse())
+			})
+		})
+
+		context("when pyproject.toml uses a caret python constraint", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+
+[tool.poetry.dependencies]
+python = "^3.8"`), 0644)).To(Succeed())
+			})
+
+			it("translates it into a semver constraint", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.PythonVersion).To(Equal(">=3.8.0, <4.0.0"))
+			})
+		})
+
+		context("when pyproject.toml uses PEP 621 [project] metadata", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[project]
+name = "some-app"
+version = "0.1.0"
+requires-python = ">=3.9,<4.0"
+dependencies = ["requests>=2.0"]
+
+[tool.poetry.dependencies]
+python = "^3.8"
+
+[build-system]
+requires = ["poetry-core>=2.0.0"]
+build-backend = "poetry.core.masonry.api"`), 0644)).To(Succeed())
+			})
+
+			it("prefers the [project] python constraint", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.Detected).To(BeTrue())
+				Expect(project.PythonVersion).To(Equal(">=3.9.0, <4.0.0"))
+				Expect(project.PythonVersionSource).To(Equal("pyproject.toml:project.requires-python"))
+			})
+		})
+
+		context("when pyproject.toml requires poetry in [build-system]", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+
+[build-system]
+requires = ["setuptools", "poetry >= 1.1, < 1.2"]
+build-backend = "poetry.masonry.api"`), 0644)).To(Succeed())
+			})
+
+			it("translates the poetry version constraint", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.PoetryVersion).To(Equal(">=1.1.0, <1.2.0"))
+			})
+
+			context("when the requirement uses PEP 440 operators", func() {
+				it("translates them into semver constraints", func() {
+					for _, c := range []struct {
+						requirement string
+						expected    string
+					}{
+						{"poetry==1.1.6", "=1.1.6"},
+						{"poetry==1.1.*", ">=1.1.0, <1.2.0"},
+						{"poetry~=1.1", ">=1.1.0, <2.0.0"},
+						{"poetry ~= 1.1.4", ">=1.1.4, <1.2.0"},
+					} {
+						Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(fmt.Sprintf(`[tool.poetry]
+name = "some-app"
+
+[build-system]
+requires = [%q]
+build-backend = "poetry.masonry.api"`, c.requirement)), 0644)).To(Succeed())
+
+						project, err := pyProjParser.Parse(workingDir)
+						Expect(err).NotTo(HaveOccurred(), c.requirement)
+						Expect(project.PoetryVersion).To(Equal(c.expected), c.requirement)
+					}
+				})
+			})
+		})
+
+		context("when pyproject.toml only requires poetry-core in [build-system]", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+
+[build-system]
+requires = ["poetry-core>=1.0.0"]
+build-backend = "poetry.core.masonry.api"`), 0644)).To(Succeed())
+			})
+
+			it("does not constrain the poetry version", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.PoetryVersion).To(BeEmpty())
+			})
+		})
+
+		context("when pyproject.toml declares scripts", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+
+[tool.poetry.scripts]
+serve = "some_app.main:serve"
+migrate = { callable = "some_app.db:migrate" }
+cli = "some_app.cli:main"`), 0644)).To(Succeed())
+			})
+
+			it("parses the script names in order", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.Scripts).To(Equal([]string{"serve", "migrate", "cli"}))
+			})
+		})
+
+		context("when pyproject.toml declares dependency groups and extras", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+
+[tool.poetry.dev-dependencies]
+pytest = "^6.2"
+
+[tool.poetry.group.docs]
+optional = true
+
+[tool.poetry.group.docs.dependencies]
+mkdocs = "^1.3"
+
+[tool.poetry.group.lint.dependencies]
+flake8 = "^4.0"
+
+[tool.poetry.extras]
+postgres = ["psycopg2"]
+
+[project.optional-dependencies]
+mysql = ["mysqlclient"]
+postgres = ["psycopg2"]`), 0644)).To(Succeed())
+			})
+
+			it("parses the group and extra names", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.Groups).To(Equal([]string{"main", "dev", "docs", "lint"}))
+				Expect(project.Extras).To(ConsistOf("postgres", "mysql"))
+			})
+		})
+
+		context("when there is no pyproject.toml", func() {
+			it("does not detect", func() {
+				project, err := pyProjParser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(project.Detected).To(BeFalse())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the python constraint cannot be satisfied", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+
+[tool.poetry.dependencies]
+python = ">=3.9,<3.8"`), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := pyProjParser.Parse(workingDir)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse python version in")))
+					Expect(err).To(MatchError(ContainSubstring("no version can satisfy it")))
+				})
+			})
+
+			context("when the poetry requirement cannot be translated", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+
+[build-system]
+requires = ["poetry>=1.2,<1.1"]
+build-backend = "poetry.masonry.api"`), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := pyProjParser.Parse(workingDir)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse poetry version in")))
+					Expect(err).To(MatchError(ContainSubstring("no version can satisfy it")))
+				})
+			})
+
+			context("when the pyproject.toml is malformed", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(filepath.Join(workingDir, "pyproject.toml"), []byte(`[tool.poetry]
+name = "some-app"
+version = %%%`), 0644)).To(Succeed())
+				})
+
+				it("returns an error with the file and line", func() {
+					_, err := pyProjParser.Parse(workingDir)
+					Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("failed to parse %s:", filepath.Join(workingDir, "pyproject.toml")))))
+					Expect(err).To(MatchError(ContainSubstring("line 3")))
+				})
+			})
+		})
 	})
 }
@@ -0,0 +1,45 @@
+package poetry
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+)
+
+// PythonVersionProcess implements the PythonVersionLookup interface.
+type PythonVersionProcess struct {
+	executable Executable
+}
+
+// NewPythonVersionProcess creates an instance of the PythonVersionProcess given an Executable that runs `python`.
+func NewPythonVersionProcess(executable Executable) PythonVersionProcess {
+	return PythonVersionProcess{
+		executable: executable,
+	}
+}
+
+// Execute runs `python --version` and returns the version of the python interpreter available on the PATH.
+func (p PythonVersionProcess) Execute() (string, error) {
+	buffer := bytes.NewBuffer(nil)
+
+	err := p.executable.Execute(pexec.Execution{
+		Args:   []string{"--version"},
+		Env:    os.Environ(),
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to determine python version:\n%s\nerror: %w", buffer.String(), err)
+	}
+
+	// The output is of the form "Python 3.8.10"
+	fields := strings.Fields(buffer.String())
+	if len(fields) != 2 || fields[0] != "Python" {
+		return "", fmt.Errorf("failed to determine python version: unexpected output %q", buffer.String())
+	}
+
+	return fields[1], nil
+}
@@ -0,0 +1,78 @@
+package poetry_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-community/poetry"
+	"github.com/paketo-community/poetry/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPythonVersionProcess(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		executable *fakes.Executable
+
+		pythonVersionProcess poetry.PythonVersionProcess
+	)
+
+	it.Before(func() {
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			fmt.Fprintln(execution.Stdout, "Python 3.8.10")
+			return nil
+		}
+
+		pythonVersionProcess = poetry.NewPythonVersionProcess(executable)
+	})
+
+	context("Execute", func() {
+		it("returns the version of python", func() {
+			version, err := pythonVersionProcess.Execute()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("3.8.10"))
+
+			Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(os.Environ()))
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"--version"}))
+		})
+
+		context("failure cases", func() {
+			context("the python version lookup fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						fmt.Fprintln(execution.Stderr, "stderr output")
+						return errors.New("running python failed")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := pythonVersionProcess.Execute()
+					Expect(err).To(MatchError(ContainSubstring("failed to determine python version:")))
+					Expect(err).To(MatchError(ContainSubstring("stderr output")))
+					Expect(err).To(MatchError(ContainSubstring("error: running python failed")))
+				})
+			})
+
+			context("the python version output is not recognized", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						fmt.Fprintln(execution.Stdout, "not a version")
+						return nil
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := pythonVersionProcess.Execute()
+					Expect(err).To(MatchError(ContainSubstring(`unexpected output "not a version\n"`)))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package poetry
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+var repositoryNameSeparators = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// Repository is a package index described by a service binding, along with
+// the credentials used to access it.
+type Repository struct {
+	// Name is the name of the repository, matching the name of a
+	// [[tool.poetry.source]] in the project.
+	Name     string
+	URL      string
+	Username string
+	Password string
+}
+
+// Env returns the environment variables that configure poetry to use the
+// repository. They contain credentials, so must only be given to the
+// processes that need them and never be written to a layer or logged.
+func (r Repository) Env() []string {
+	name := strings.Trim(repositoryNameSeparators.ReplaceAllString(strings.ToUpper(r.Name), "_"), "_")
+
+	env := []string{fmt.Sprintf("POETRY_REPOSITORIES_%s_URL=%s", name, r.URL)}
+	if r.Username != "" {
+		env = append(env,
+			fmt.Sprintf("POETRY_HTTP_BASIC_%s_USERNAME=%s", name, r.Username),
+			fmt.Sprintf("POETRY_HTTP_BASIC_%s_PASSWORD=%s", name, r.Password),
+		)
+	}
+
+	return env
+}
+
+// Repositories returns the repositories described by the service bindings of
+// type "poetry" or "pip", sorted by name. Bindings are resolved as packit
+// does, from $SERVICE_BINDING_ROOT, $CNB_BINDINGS or the bindings directory of
+// the given platform path. Each binding must provide a "url" entry, and may
+// provide a "name" entry, which otherwise defaults to the name of the binding,
+// along with "username" and "password" entries. A "pip" binding without a
+// "url" entry is skipped, since it configures pip alone, such as through a
+// pip.conf entry. The values of entries are never included in the returned
+// errors.
+func Repositories(platformPath string) ([]Repository, error) {
+	resolver := servicebindings.NewResolver()
+
+	var repositories []Repository
+	for _, bindingType := range []string{"poetry", "pip"} {
+		bindings, err := resolver.Resolve(bindingType, "", platformPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service bindings: %w", err)
+		}
+
+		for _, binding := range bindings {
+			entries, err := bindingEntries(binding, "name", "url", "username", "password")
+			if err != nil {
+				return nil, err
+			}
+
+			repository := Repository{
+				Name:     entries["name"],
+				URL:      entries["url"],
+				Username: entries["username"],
+				Password: entries["password"],
+			}
+
+			if repository.Name == "" {
+				repository.Name = binding.Name
+			}
+
+			if repository.URL == "" {
+				if bindingType == "pip" {
+					continue
+				}
+
+				return nil, fmt.Errorf("invalid service binding %s: missing required entry \"url\"", binding.Path)
+			}
+
+			if (repository.Username == "") != (repository.Password == "") {
+				return nil, fmt.Errorf("invalid service binding %s: entries \"username\" and \"password\" must be provided together", binding.Path)
+			}
+
+			repositories = append(repositories, repository)
+		}
+	}
+
+	sort.Slice(repositories, func(i, j int) bool {
+		return repositories[i].Name < repositories[j].Name
+	})
+
+	return repositories, nil
+}
+
+func bindingEntries(binding servicebindings.Binding, names ...string) (map[string]string, error) {
+	entries := map[string]string{}
+	for _, name := range names {
+		entry, ok := binding.Entries[name]
+		if !ok {
+			continue
+		}
+
+		content, err := entry.ReadString()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service binding entry %s: %w", filepath.Join(binding.Path, name), err)
+		}
+
+		entries[name] = strings.TrimSpace(content)
+	}
+
+	return entries, nil
+}
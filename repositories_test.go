@@ -0,0 +1,187 @@
+package poetry_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-community/poetry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRepositories(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		platformDir string
+		bindingsDir string
+	)
+
+	writeBinding := func(name string, entries map[string]string) {
+		Expect(os.MkdirAll(filepath.Join(bindingsDir, name), os.ModePerm)).To(Succeed())
+		for key, value := range entries {
+			Expect(ioutil.WriteFile(filepath.Join(bindingsDir, name, key), []byte(value), 0600)).To(Succeed())
+		}
+	}
+
+	it.Before(func() {
+		var err error
+		platformDir, err = ioutil.TempDir("", "platform")
+		Expect(err).NotTo(HaveOccurred())
+
+		bindingsDir = filepath.Join(platformDir, "bindings")
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(platformDir)).To(Succeed())
+	})
+
+	context("Repositories", func() {
+		context("when there are no bindings", func() {
+			it("returns no repositories", func() {
+				repositories, err := poetry.Repositories(platformDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(repositories).To(BeEmpty())
+			})
+		})
+
+		context("when there are bindings", func() {
+			it.Before(func() {
+				writeBinding("private-index", map[string]string{
+					"type":     "poetry",
+					"url":      "https://index.example.com/simple\n",
+					"username": "some-user",
+					"password": "some-password",
+				})
+				writeBinding("other-binding", map[string]string{
+					"type": "pip",
+					"name": "mirror",
+					"url":  "https://mirror.example.com/simple",
+				})
+				writeBinding("pip-config", map[string]string{
+					"type":     "pip",
+					"pip.conf": "[global]\nindex-url = https://pip.example.com/simple",
+				})
+				writeBinding("unrelated-binding", map[string]string{
+					"type": "mysql",
+					"url":  "mysql://db.example.com",
+				})
+			})
+
+			it("returns the repositories of the poetry and pip bindings that provide a url", func() {
+				repositories, err := poetry.Repositories(platformDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(repositories).To(Equal([]poetry.Repository{
+					{
+						Name: "mirror",
+						URL:  "https://mirror.example.com/simple",
+					},
+					{
+						Name:     "private-index",
+						URL:      "https://index.example.com/simple",
+						Username: "some-user",
+						Password: "some-password",
+					},
+				}))
+			})
+
+			context("when SERVICE_BINDING_ROOT is set", func() {
+				var bindingRoot string
+
+				it.Before(func() {
+					var err error
+					bindingRoot, err = ioutil.TempDir("", "bindings")
+					Expect(err).NotTo(HaveOccurred())
+
+					bindingsDir = bindingRoot
+					writeBinding("root-index", map[string]string{
+						"type": "poetry",
+						"url":  "https://root.example.com/simple",
+					})
+
+					Expect(os.Setenv("SERVICE_BINDING_ROOT", bindingRoot)).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("SERVICE_BINDING_ROOT")).To(Succeed())
+					Expect(os.RemoveAll(bindingRoot)).To(Succeed())
+				})
+
+				it("reads the bindings from it instead", func() {
+					repositories, err := poetry.Repositories(platformDir)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(repositories).To(Equal([]poetry.Repository{
+						{
+							Name: "root-index",
+							URL:  "https://root.example.com/simple",
+						},
+					}))
+				})
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a binding has no url", func() {
+				it.Before(func() {
+					writeBinding("private-index", map[string]string{
+						"type": "poetry",
+					})
+				})
+
+				it("returns an error", func() {
+					_, err := poetry.Repositories(platformDir)
+					Expect(err).To(MatchError(ContainSubstring(`invalid service binding %s: missing required entry "url"`, filepath.Join(bindingsDir, "private-index"))))
+				})
+			})
+
+			context("when a binding has a username but no password", func() {
+				it.Before(func() {
+					writeBinding("private-index", map[string]string{
+						"type":     "poetry",
+						"url":      "https://index.example.com/simple",
+						"username": "some-user",
+					})
+				})
+
+				it("returns an error that does not contain the username", func() {
+					_, err := poetry.Repositories(platformDir)
+					Expect(err).To(MatchError(ContainSubstring(`entries "username" and "password" must be provided together`)))
+					Expect(err).NotTo(MatchError(ContainSubstring("some-user")))
+				})
+			})
+		})
+	})
+
+	context("Repository.Env", func() {
+		it("returns the poetry configuration for the repository", func() {
+			repository := poetry.Repository{
+				Name:     "private.index",
+				URL:      "https://index.example.com/simple",
+				Username: "some-user",
+				Password: "some-password",
+			}
+
+			Expect(repository.Env()).To(Equal([]string{
+				"POETRY_REPOSITORIES_PRIVATE_INDEX_URL=https://index.example.com/simple",
+				"POETRY_HTTP_BASIC_PRIVATE_INDEX_USERNAME=some-user",
+				"POETRY_HTTP_BASIC_PRIVATE_INDEX_PASSWORD=some-password",
+			}))
+		})
+
+		context("when the repository has no credentials", func() {
+			it("only configures the url", func() {
+				repository := poetry.Repository{
+					Name: "mirror",
+					URL:  "https://mirror.example.com/simple",
+				}
+
+				Expect(repository.Env()).To(Equal([]string{
+					"POETRY_REPOSITORIES_MIRROR_URL=https://mirror.example.com/simple",
+				}))
+			})
+		})
+	})
+}
@@ -1,23 +1,34 @@
 package main
 
 import (
-	"github.com/paketo-buildpacks/packit"
-	"github.com/paketo-buildpacks/packit/cargo"
-	"github.com/paketo-buildpacks/packit/draft"
-	"github.com/paketo-buildpacks/packit/pexec"
-	"github.com/paketo-buildpacks/packit/postal"
+	"os"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+	"github.com/paketo-buildpacks/packit/v2/draft"
+	"github.com/paketo-buildpacks/packit/v2/fs"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/postal"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 	"github.com/paketo-community/poetry"
 )
 
 func main() {
+	logger := scribe.NewEmitter(os.Stdout)
 	pyProjectParser := poetry.NewPyProjParser()
 	dependencyManager := postal.NewService(cargo.NewTransport())
 	entryResolver := draft.NewPlanner()
-	installProcess := poetry.NewPoetryInstallProcess(pexec.NewExecutable("pip"))
-	siteProcess := poetry.NewSiteProcess(pexec.NewExecutable("python"))
+	installProcess := poetry.NewPoetryInstallProcess(pexec.NewExecutable("python"), logger)
+	verifyProcess := poetry.NewPoetryVerifyProcess(pexec.NewExecutable("poetry"), logger)
+	pythonVersionProcess := poetry.NewPythonVersionProcess(pexec.NewExecutable("python"))
+	venvInstallProcess := poetry.NewPoetryVenvInstallProcess(pexec.NewExecutable("poetry"), logger)
+	exportProcess := poetry.NewPoetryExportProcess(pexec.NewExecutable("poetry"), logger)
+	calculator := fs.NewChecksumCalculator()
+	sbomGenerator := poetry.NewPythonSBOMGenerator()
 
 	packit.Run(
-		poetry.Detect(pyProjectParser),
-		poetry.Build(dependencyManager, entryResolver, installProcess, siteProcess),
+		poetry.Detect(pyProjectParser, logger),
+		poetry.Build(dependencyManager, entryResolver, installProcess, verifyProcess, pythonVersionProcess, venvInstallProcess, exportProcess, calculator, sbomGenerator, pyProjectParser, logger, chronos.DefaultClock),
 	)
 }
@@ -0,0 +1,20 @@
+package poetry
+
+import (
+	"github.com/paketo-buildpacks/packit/v2/sbom"
+)
+
+// PythonSBOMGenerator implements the SBOMGenerator interface.
+type PythonSBOMGenerator struct{}
+
+// NewPythonSBOMGenerator creates an instance of the PythonSBOMGenerator.
+func NewPythonSBOMGenerator() PythonSBOMGenerator {
+	return PythonSBOMGenerator{}
+}
+
+// Generate catalogs the python packages installed below the given path, from
+// the *.dist-info metadata of each package, along with any poetry.lock found
+// there.
+func (g PythonSBOMGenerator) Generate(path string) (sbom.SBOM, error) {
+	return sbom.Generate(path)
+}
@@ -0,0 +1,67 @@
+package poetry_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/sbom"
+	"github.com/paketo-community/poetry"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPythonSBOMGenerator(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layerDir string
+
+		generator poetry.PythonSBOMGenerator
+	)
+
+	it.Before(func() {
+		var err error
+		layerDir, err = ioutil.TempDir("", "layer")
+		Expect(err).NotTo(HaveOccurred())
+
+		distInfo := filepath.Join(layerDir, "lib", "python3.8", "site-packages", "requests-2.25.1.dist-info")
+		Expect(os.MkdirAll(distInfo, os.ModePerm)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(distInfo, "METADATA"), []byte(`Metadata-Version: 2.1
+Name: requests
+Version: 2.25.1
+License: Apache 2.0
+`), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(distInfo, "RECORD"), nil, 0644)).To(Succeed())
+
+		generator = poetry.NewPythonSBOMGenerator()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(layerDir)).To(Succeed())
+	})
+
+	context("Generate", func() {
+		it("catalogs the installed packages", func() {
+			content, err := generator.Generate(layerDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			formatter, err := content.InFormats(sbom.CycloneDXFormat, sbom.SPDXFormat, sbom.SyftFormat)
+			Expect(err).NotTo(HaveOccurred())
+
+			formats := formatter.Formats()
+			Expect(formats).To(HaveLen(3))
+			for _, format := range formats {
+				document := bytes.NewBuffer(nil)
+				_, err = document.ReadFrom(format.Content)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(document.String()).To(ContainSubstring(`"requests"`), format.Extension)
+				Expect(document.String()).To(ContainSubstring("pkg:pypi/requests@2.25.1"), format.Extension)
+			}
+		})
+	})
+}
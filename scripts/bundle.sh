@@ -0,0 +1,175 @@
+#!/usr/bin/env bash
+
+set -eu
+set -o pipefail
+
+readonly ROOT_DIR="$(cd "$(dirname "${0}")/.." && pwd)"
+
+# shellcheck source=SCRIPTDIR/.util/print.sh
+source "${ROOT_DIR}/scripts/.util/print.sh"
+
+function main {
+  local buildpack_dir
+  local -a python_versions
+  python_versions=()
+
+  while [[ "${#}" != 0 ]]; do
+    case "${1}" in
+      --buildpack|-b)
+        buildpack_dir="${2}"
+        shift 2
+        ;;
+
+      --python-version|-p)
+        python_versions+=("${2}")
+        shift 2
+        ;;
+
+      --help|-h)
+        shift 1
+        usage
+        exit 0
+        ;;
+
+      "")
+        # skip if the argument is empty
+        shift 1
+        ;;
+
+      *)
+        util::print::error "unknown argument \"${1}\""
+    esac
+  done
+
+  if [[ -z "${buildpack_dir:-}" ]]; then
+    usage
+    echo
+    util::print::error "--buildpack is required"
+  fi
+
+  if [[ "${#python_versions[@]}" == 0 ]]; then
+    python_versions=("3.6" "3.7" "3.8" "3.9")
+  fi
+
+  local version
+  for version in $(dependency::versions "${buildpack_dir}/buildpack.toml"); do
+    dependency::bundle "${buildpack_dir}" "${version}" "${python_versions[@]}"
+  done
+}
+
+function usage() {
+  cat <<-USAGE
+bundle.sh --buildpack <directory> [OPTIONS]
+
+Bundles each poetry dependency of the buildpack with the wheels of poetry and
+all of its dependencies, so that poetry installs without a package index. The
+buildpack.toml in the given directory is rewritten to point at the bundles, so
+run this against a copy of the repository, never the repository itself.
+
+OPTIONS
+  --help                       -h                 prints the command usage
+  --buildpack <directory>      -b <directory>     directory containing the buildpack.toml to rewrite
+  --python-version <version>   -p <version>       python version to download wheels for, may be given more than once (default: 3.6 3.7 3.8 3.9)
+USAGE
+}
+
+function dependency::versions() {
+  local buildpack_toml
+  buildpack_toml="${1}"
+
+  awk '
+    /^\[\[/ { section = $0; id = "" }
+    section == "[[metadata.dependencies]]" && /^id = / { id = $3 }
+    section == "[[metadata.dependencies]]" && id == "\"poetry\"" && /^version = / { gsub(/"/, "", $3); print $3 }
+  ' "${buildpack_toml}"
+}
+
+function dependency::bundle() {
+  local buildpack_dir version
+  buildpack_dir="${1}"
+  version="${2}"
+  shift 2
+
+  util::print::title "Bundling poetry ${version} with its wheels..."
+
+  local wheels_dir
+  wheels_dir="$(mktemp -d)"
+
+  # Wheels are downloaded for every python version the bundle may be installed
+  # with, as some of poetry's dependencies only publish version specific wheels.
+  local python_version
+  for python_version in "${@}"; do
+    util::print::info "  Downloading wheels for python ${python_version}"
+
+    python3 -m pip download \
+      "poetry==${version}" \
+      --dest "${wheels_dir}" \
+      --only-binary ":all:" \
+      --implementation cp \
+      --python-version "${python_version}" \
+      --platform manylinux2014_x86_64 \
+      --platform manylinux2010_x86_64 \
+      --platform manylinux1_x86_64 \
+      --quiet
+  done
+
+  local bundle
+  bundle="dependencies/poetry-${version}-bundle.tgz"
+  mkdir -p "${buildpack_dir}/dependencies"
+
+  tar --create \
+    --sort=name \
+    --mtime="1970-01-01 00:00:00Z" \
+    --owner=0 --group=0 --numeric-owner \
+    --directory "${wheels_dir}" \
+    . | gzip --no-name > "${buildpack_dir}/${bundle}"
+
+  rm -rf "${wheels_dir}"
+
+  local sha256
+  sha256="$(sha256sum "${buildpack_dir}/${bundle}" | cut -d ' ' -f 1)"
+
+  util::print::info "  Bundled ${bundle} (sha256:${sha256})"
+
+  dependency::rewrite "${buildpack_dir}/buildpack.toml" "${version}" "${bundle}" "${sha256}"
+}
+
+# Points the poetry dependency of the given version at the bundle. The source
+# of the dependency is left untouched, so that it still names the sdist the
+# bundled poetry was released as.
+function dependency::rewrite() {
+  local buildpack_toml version bundle sha256
+  buildpack_toml="${1}"
+  version="${2}"
+  bundle="${3}"
+  sha256="${4}"
+
+  awk \
+    -v version="\"${version}\"" \
+    -v uri="\"file://${bundle}\"" \
+    -v sha256="\"${sha256}\"" \
+    -v bundle="${bundle}" '
+    function flush() {
+      for (i = 1; i <= n; i++) {
+        line = block[i]
+        if (section == "[[metadata.dependencies]]" && id == "\"poetry\"" && current == version) {
+          if (line ~ /^uri = /) { line = "uri = " uri }
+          if (line ~ /^sha256 = /) { line = "sha256 = " sha256 }
+        }
+        print line
+      }
+      n = 0; id = ""; current = ""
+    }
+
+    /^\[/ { flush(); section = $0 }
+    /^id = / { id = $3 }
+    /^version = / { current = $3 }
+    /^include-files = / { sub(/\]$/, ",\"" bundle "\"]") }
+    { block[++n] = $0 }
+    END { flush() }
+  ' "${buildpack_toml}" > "${buildpack_toml}.tmp"
+
+  mv "${buildpack_toml}.tmp" "${buildpack_toml}"
+}
+
+main "${@:-}"
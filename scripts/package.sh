@@ -6,6 +6,7 @@ set -o pipefail
 readonly ROOT_DIR="$(cd "$(dirname "${0}")/.." && pwd)"
 readonly BIN_DIR="${ROOT_DIR}/.bin"
 readonly BUILD_DIR="${ROOT_DIR}/build"
+readonly STAGE_DIR="${BUILD_DIR}/source"
 
 # shellcheck source=SCRIPTDIR/.util/tools.sh
 source "${ROOT_DIR}/scripts/.util/tools.sh"
@@ -55,6 +56,7 @@ function main {
   fi
 
   repo::prepare
+  buildpack::stage
   buildpack::archive "${version}"
   buildpackage::create "${output}"
 }
@@ -83,6 +85,22 @@ function repo::prepare() {
   export PATH="${BIN_DIR}:${PATH}"
 }
 
+# Packages a copy of the repository, as bundling the poetry dependencies
+# rewrites the buildpack.toml that is packaged.
+function buildpack::stage() {
+  util::print::title "Staging buildpack into ${STAGE_DIR}..."
+
+  mkdir -p "${STAGE_DIR}"
+  tar --create \
+    --exclude "./.git" \
+    --exclude "./.bin" \
+    --exclude "./build" \
+    --directory "${ROOT_DIR}" \
+    . | tar --extract --directory "${STAGE_DIR}"
+
+  "${ROOT_DIR}/scripts/bundle.sh" --buildpack "${STAGE_DIR}"
+}
+
 function buildpack::archive() {
   local version
   version="${1}"
@@ -93,7 +111,7 @@ function buildpack::archive() {
     util::tools::jam::install --directory "${BIN_DIR}"
 
     jam pack \
-      --buildpack "${ROOT_DIR}/buildpack.toml" \
+      --buildpack "${STAGE_DIR}/buildpack.toml" \
       --version "${version}" \
       --output "${BUILD_DIR}/buildpack.tgz"
   else